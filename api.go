@@ -5,12 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"time"
 )
 
 // Generate sends a generation request to the Ollama API
 func (c *Client) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
 	req.Stream = false
-	resp, err := c.sendRequest(ctx, "POST", "/api/generate", req)
+	call := newCall("POST", "/api/generate", req, false)
+	resp, err := c.do(ctx, call)
 	if err != nil {
 		return nil, err
 	}
@@ -18,8 +20,11 @@ func (c *Client) Generate(ctx context.Context, req *GenerateRequest) (*GenerateR
 
 	var result GenerateResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		call.Err = err
 		return nil, err
 	}
+	call.Result = &result
+	c.recordUsage(call, result.Model, req.Model, result.Usage())
 
 	return &result, nil
 }
@@ -28,7 +33,8 @@ func (c *Client) Generate(ctx context.Context, req *GenerateRequest) (*GenerateR
 func (c *Client) GenerateStream(ctx context.Context, req *GenerateRequest) (<-chan GenerateStreamResponse, error) {
 	req.Stream = true
 
-	resp, err := c.sendRequest(ctx, "POST", "/api/generate", req)
+	call := newCall("POST", "/api/generate", req, true)
+	resp, err := c.do(ctx, call)
 	if err != nil {
 		return nil, err
 	}
@@ -38,21 +44,21 @@ func (c *Client) GenerateStream(ctx context.Context, req *GenerateRequest) (<-ch
 		defer close(ch)
 		defer resp.Body.Close()
 
-		decoder := json.NewDecoder(resp.Body)
-		for {
-			var response GenerateResponse
-			if err := decoder.Decode(&response); err != nil {
-				if err != io.EOF {
-					ch <- GenerateStreamResponse{
-						Error: err,
-					}
-				}
-				return
+		decodeStream(resp.Body, func(response *GenerateResponse, err error) {
+			if call.TTFT == 0 {
+				call.TTFT = time.Since(call.Start)
+			}
+			if err != nil {
+				call.Err = err
+			} else if response.Done {
+				call.Result = response
+				c.recordUsage(call, response.Model, req.Model, response.Usage())
 			}
 			ch <- GenerateStreamResponse{
-				GenerateResponse: &response,
+				GenerateResponse: response,
+				Error:            err,
 			}
-		}
+		})
 	}()
 
 	return ch, nil
@@ -60,7 +66,8 @@ func (c *Client) GenerateStream(ctx context.Context, req *GenerateRequest) (<-ch
 
 // Chat sends a chat request to the Ollama API
 func (c *Client) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
-	resp, err := c.sendRequest(ctx, "POST", "/api/chat", req)
+	call := newCall("POST", "/api/chat", req, false)
+	resp, err := c.do(ctx, call)
 	if err != nil {
 		return nil, err
 	}
@@ -68,26 +75,32 @@ func (c *Client) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, err
 
 	var result ChatResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		call.Err = err
 		return nil, err
 	}
+	call.Result = &result
+	c.recordUsage(call, result.Model, req.Model, result.Usage())
 
 	return &result, nil
 }
 
 // ListModels returns a list of local models
-func (c *Client) ListModels(ctx context.Context) ([]ModelInfo, error) {
-	resp, err := c.sendRequest(ctx, "GET", "/api/tags", nil)
+func (c *Client) ListModels(ctx context.Context) ([]ListedModel, error) {
+	call := newCall("GET", "/api/tags", nil, false)
+	resp, err := c.do(ctx, call)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	var result struct {
-		Models []ModelInfo `json:"models"`
+		Models []ListedModel `json:"models"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		call.Err = err
 		return nil, err
 	}
+	call.Result = &result
 
 	return result.Models, nil
 }
@@ -98,7 +111,7 @@ type ShowModelOptions struct {
 }
 
 // ShowModel returns information about a specific model
-func (c *Client) ShowModel(ctx context.Context, name string, opts *ShowModelOptions) (*ModelInfo, error) {
+func (c *Client) ShowModel(ctx context.Context, name string, opts *ShowModelOptions) (*ShowModelResponse, error) {
 	// Create request
 	req := struct {
 		Model   string `json:"model"`
@@ -112,34 +125,96 @@ func (c *Client) ShowModel(ctx context.Context, name string, opts *ShowModelOpti
 	}
 
 	// Send POST request
-	resp, err := c.sendRequest(ctx, "POST", "/api/show", req)
+	call := newCall("POST", "/api/show", req, false)
+	resp, err := c.do(ctx, call)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	var result ModelInfo
+	var result ShowModelResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		call.Err = err
 		return nil, err
 	}
+	call.Result = &result
 
 	return &result, nil
 }
 
-// CreateModel creates a new model
+// CreateModel creates a new model and waits for it to be created
 func (c *Client) CreateModel(ctx context.Context, req *CreateModelRequest) error {
-	resp, err := c.sendRequest(ctx, "POST", "/api/create", req)
+	req.Stream = false
+	req, err := c.uploadLocalFiles(ctx, req, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create model: %w", err)
+	}
+	call := newCall("POST", "/api/create", req, false)
+	resp, err := c.do(ctx, call)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
+	decodeStream(resp.Body, func(response *ModelResponse, e error) {
+		err = e
+	})
+	if err != nil {
+		call.Err = err
+		return fmt.Errorf("failed to create model: %w", err)
+	}
 	return nil
 }
 
+// CreateModelStream creates a new model, streaming its progress, which
+// starts with the upload of any local files. A failure is reported in the
+// Error field of the last response.
+func (c *Client) CreateModelStream(ctx context.Context, req *CreateModelRequest) (<-chan ModelResponse, error) {
+	req.Stream = true
+	if len(req.LocalFiles) == 0 && len(req.LocalAdapters) == 0 {
+		return c.modelStream(ctx, "/api/create", req)
+	}
+
+	ch := make(chan ModelResponse)
+	// send gives up once ctx is done so that an abandoned stream does not
+	// keep the goroutine and the file being uploaded open.
+	send := func(response ModelResponse) bool {
+		select {
+		case ch <- response:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	go func() {
+		defer close(ch)
+		r, err := c.uploadLocalFiles(ctx, req, func(progress ModelResponse) {
+			send(progress)
+		})
+		if err == nil {
+			var stream <-chan ModelResponse
+			if stream, err = c.modelStream(ctx, "/api/create", r); err == nil {
+				for response := range stream {
+					if !send(response) {
+						// The request is cancelled too, so the stream ends soon
+						for range stream {
+						}
+						return
+					}
+				}
+				return
+			}
+		}
+		send(ModelResponse{Error: err})
+	}()
+
+	return ch, nil
+}
+
 // CopyModel copies a model
 func (c *Client) CopyModel(ctx context.Context, req *CopyModelRequest) error {
-	resp, err := c.sendRequest(ctx, "POST", "/api/copy", req)
+	call := newCall("POST", "/api/copy", req, false)
+	resp, err := c.do(ctx, call)
 	if err != nil {
 		return err
 	}
@@ -156,67 +231,90 @@ func (c *Client) DeleteModel(ctx context.Context, name string) error {
 		Model: name,
 	}
 
-	_, err := c.sendRequest(ctx, "DELETE", "/api/delete", reqBody)
+	call := newCall("DELETE", "/api/delete", reqBody, false)
+	resp, err := c.do(ctx, call)
 	if err != nil {
 		return fmt.Errorf("failed to delete model: %w", err)
 	}
+	resp.Body.Close()
 
 	return nil
 }
 
-// PullModel pulls a model from a registry
-func (c *Client) PullModel(ctx context.Context, req *PullModelRequest) (<-chan ModelResponse, error) {
-	req.Stream = true
-	resp, err := c.sendRequest(ctx, "POST", "/api/pull", req)
-	if err != nil {
-		return nil, err
+// loadRequest is a generate request without a prompt, which loads or
+// unloads a model.
+type loadRequest struct {
+	Model     string   `json:"model"`
+	Stream    bool     `json:"stream"`
+	KeepAlive Duration `json:"keep_alive,omitempty"`
+}
+
+// LoadModel loads a model into memory and keeps it loaded for keepAlive
+// after its last use. Zero uses the server's default and a negative
+// keepAlive keeps the model loaded until UnloadModel.
+func (c *Client) LoadModel(ctx context.Context, name string, keepAlive time.Duration) error {
+	req := loadRequest{Model: name, KeepAlive: Duration(keepAlive)}
+	if keepAlive < 0 {
+		req.KeepAlive = KeepForever
 	}
+	if err := c.sendLoad(ctx, req); err != nil {
+		return fmt.Errorf("failed to load model: %w", err)
+	}
+	return nil
+}
 
-	ch := make(chan ModelResponse)
-	go func() {
-		defer resp.Body.Close()
-		defer close(ch)
+// UnloadModel unloads a model from memory
+func (c *Client) UnloadModel(ctx context.Context, name string) error {
+	if err := c.sendLoad(ctx, loadRequest{Model: name, KeepAlive: UnloadNow}); err != nil {
+		return fmt.Errorf("failed to unload model: %w", err)
+	}
+	return nil
+}
 
-		decoder := json.NewDecoder(resp.Body)
-		for {
-			var response ModelResponse
-			if err := decoder.Decode(&response); err != nil {
-				if err != io.EOF {
-					// Handle error
-				}
-				return
-			}
-			ch <- response
-		}
-	}()
+func (c *Client) sendLoad(ctx context.Context, req loadRequest) error {
+	call := newCall("POST", "/api/generate", req, false)
+	resp, err := c.do(ctx, call)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
 
-	return ch, nil
+// PullModel pulls a model from a registry
+func (c *Client) PullModel(ctx context.Context, req *PullModelRequest) (<-chan ModelResponse, error) {
+	req.Stream = true
+	return c.modelStream(ctx, "/api/pull", req)
 }
 
 // PushModel pushes a model to a registry
 func (c *Client) PushModel(ctx context.Context, req *PushModelRequest) (<-chan ModelResponse, error) {
 	req.Stream = true
-	resp, err := c.sendRequest(ctx, "POST", "/api/push", req)
+	return c.modelStream(ctx, "/api/push", req)
+}
+
+// modelStream sends a pull, push or create request and streams its
+// progress.
+func (c *Client) modelStream(ctx context.Context, endpoint string, req interface{}) (<-chan ModelResponse, error) {
+	call := newCall("POST", endpoint, req, true)
+	resp, err := c.do(ctx, call)
 	if err != nil {
 		return nil, err
 	}
 
 	ch := make(chan ModelResponse)
 	go func() {
-		defer resp.Body.Close()
 		defer close(ch)
+		defer resp.Body.Close()
 
-		decoder := json.NewDecoder(resp.Body)
-		for {
-			var response ModelResponse
-			if err := decoder.Decode(&response); err != nil {
-				if err != io.EOF {
-					// Handle error
-				}
+		decodeStream(resp.Body, func(response *ModelResponse, err error) {
+			if err != nil {
+				call.Err = err
+				ch <- ModelResponse{Error: err}
 				return
 			}
-			ch <- response
-		}
+			ch <- *response
+		})
 	}()
 
 	return ch, nil
@@ -224,7 +322,8 @@ func (c *Client) PushModel(ctx context.Context, req *PushModelRequest) (<-chan M
 
 // Embeddings generates embeddings for the given input
 func (c *Client) Embeddings(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
-	resp, err := c.sendRequest(ctx, "POST", "/api/embeddings", req)
+	call := newCall("POST", "/api/embeddings", req, false)
+	resp, err := c.do(ctx, call)
 	if err != nil {
 		return nil, err
 	}
@@ -232,26 +331,31 @@ func (c *Client) Embeddings(ctx context.Context, req *EmbeddingRequest) (*Embedd
 
 	var result EmbeddingResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		call.Err = err
 		return nil, err
 	}
+	call.Result = &result
 
 	return &result, nil
 }
 
 // ListRunningModels returns a list of currently running models
-func (c *Client) ListRunningModels(ctx context.Context) ([]ModelInfo, error) {
-	resp, err := c.sendRequest(ctx, "GET", "/api/ps", nil)
+func (c *Client) ListRunningModels(ctx context.Context) ([]RunningModel, error) {
+	call := newCall("GET", "/api/ps", nil, false)
+	resp, err := c.do(ctx, call)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	var result struct {
-		Models []ModelInfo `json:"models"`
+		Models []RunningModel `json:"models"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		call.Err = err
 		return nil, err
 	}
+	call.Result = &result
 
 	return result.Models, nil
 }
@@ -259,31 +363,64 @@ func (c *Client) ListRunningModels(ctx context.Context) ([]ModelInfo, error) {
 // ChatStream sends a streaming chat request to the Ollama API
 func (c *Client) ChatStream(ctx context.Context, req *ChatRequest) (<-chan ChatStreamResponse, error) {
 	req.Stream = true
-	resp, err := c.sendRequest(ctx, "POST", "/api/chat", req)
+	call := newCall("POST", "/api/chat", req, true)
+	resp, err := c.do(ctx, call)
 	if err != nil {
 		return nil, err
 	}
 
 	ch := make(chan ChatStreamResponse)
 	go func() {
-		defer resp.Body.Close()
 		defer close(ch)
-		decoder := json.NewDecoder(resp.Body)
-		for {
-			var response ChatResponse
-			if err := decoder.Decode(&response); err != nil {
-				if err != io.EOF {
-					ch <- ChatStreamResponse{
-						Error: err,
-					}
-				}
-				return
+		defer resp.Body.Close()
+
+		decodeStream(resp.Body, func(response *ChatResponse, err error) {
+			if call.TTFT == 0 {
+				call.TTFT = time.Since(call.Start)
+			}
+			if err != nil {
+				call.Err = err
+			} else if response.Done {
+				call.Result = response
+				c.recordUsage(call, response.Model, req.Model, response.Usage())
 			}
 			ch <- ChatStreamResponse{
-				ChatResponse: &response,
+				ChatResponse: response,
+				Error:        err,
 			}
-		}
+		})
 	}()
 
 	return ch, nil
 }
+
+// decodeStream decodes an NDJSON response body, calling emit for every chunk.
+// A decode failure or an {"error": ...} object sent by the server in place of
+// a chunk is passed to emit as an error and ends the stream.
+func decodeStream[T any](body io.Reader, emit func(*T, error)) {
+	decoder := json.NewDecoder(body)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if err != io.EOF {
+				emit(nil, err)
+			}
+			return
+		}
+
+		var streamErr struct {
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(raw, &streamErr); err == nil && streamErr.Error != "" {
+			emit(nil, fmt.Errorf("stream error: %s", streamErr.Error))
+			return
+		}
+
+		var chunk T
+		if err := json.Unmarshal(raw, &chunk); err != nil {
+			emit(nil, err)
+			return
+		}
+		emit(&chunk, nil)
+	}
+}
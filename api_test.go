@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -102,8 +104,9 @@ func TestGenerateStream(t *testing.T) {
 	var received []GenerateResponse
 	for response := range stream {
 		if response.Error != nil {
-			received = append(received, *response.GenerateResponse)
+			t.Fatalf("GenerateStream() stream error = %v", response.Error)
 		}
+		received = append(received, *response.GenerateResponse)
 	}
 
 	if !reflect.DeepEqual(received, responses) {
@@ -162,13 +165,24 @@ func TestChat(t *testing.T) {
 }
 
 func TestListModels(t *testing.T) {
-	expectedModels := []ModelInfo{
-		{Name: "llama3.2:1b", Size: 1000},
+	expectedModels := []ListedModel{
+		{
+			Name:       "llama3.2:1b",
+			Model:      "llama3.2:1b",
+			ModifiedAt: time.Date(2024, 9, 25, 10, 0, 0, 0, time.UTC),
+			Size:       1000,
+			Digest:     "baf6a787fdff",
+			Details:    ModelDetails{Format: "gguf", Family: "llama", Families: []string{"llama"}, ParameterSize: "1.2B", QuantizationLevel: "Q8_0"},
+		},
 		{Name: "mistral", Size: 2000},
 	}
 
 	server, client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
-		json.NewEncoder(w).Encode(map[string][]ModelInfo{"models": expectedModels})
+		io.WriteString(w, `{"models":[
+			{"name":"llama3.2:1b","model":"llama3.2:1b","modified_at":"2024-09-25T10:00:00Z","size":1000,"digest":"baf6a787fdff",
+			 "details":{"parent_model":"","format":"gguf","family":"llama","families":["llama"],"parameter_size":"1.2B","quantization_level":"Q8_0"}},
+			{"name":"mistral","size":2000}
+		]}`)
 	})
 	defer server.Close()
 
@@ -178,7 +192,7 @@ func TestListModels(t *testing.T) {
 	}
 
 	if !reflect.DeepEqual(models, expectedModels) {
-		t.Errorf("ListModels() got = %v, want %v", models, expectedModels)
+		t.Errorf("ListModels() got = %+v, want %+v", models, expectedModels)
 	}
 }
 
@@ -188,7 +202,7 @@ func TestShowModel(t *testing.T) {
 		modelName    string
 		opts         *ShowModelOptions
 		expectedBody map[string]interface{}
-		response     *ModelInfo
+		response     *ShowModelResponse
 		wantErr      bool
 	}{
 		{
@@ -198,9 +212,9 @@ func TestShowModel(t *testing.T) {
 			expectedBody: map[string]interface{}{
 				"model": "llama2",
 			},
-			response: &ModelInfo{
-				Name: "llama2",
-				Size: 1000,
+			response: &ShowModelResponse{
+				Modelfile: "FROM llama2",
+				Details:   ModelDetails{Format: "gguf", Family: "llama"},
 			},
 			wantErr: false,
 		},
@@ -214,16 +228,16 @@ func TestShowModel(t *testing.T) {
 				"model":   "llama2",
 				"verbose": true,
 			},
-			response: &ModelInfo{
-				Name:      "llama2",
-				Size:      1000,
+			response: &ShowModelResponse{
 				Modelfile: "FROM llama2\nPARAMETER temperature 0.7",
 				Template:  "<prompt>",
 				License:   "MIT",
-				Details: map[string]interface{}{
-					"format":       "gguf",
-					"quantization": "q4_0",
+				Details: ModelDetails{
+					Format:            "gguf",
+					QuantizationLevel: "Q4_0",
 				},
+				Tensors:    []ModelTensor{{Name: "token_embd.weight", Type: "Q4_0", Shape: []uint64{4096, 32000}}},
+				ModifiedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
 			},
 			wantErr: false,
 		},
@@ -327,6 +341,62 @@ func TestCreateModel(t *testing.T) {
 	}
 }
 
+func TestCreateModelStream(t *testing.T) {
+	var body map[string]interface{}
+	server, client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		io.WriteString(w, `{"status":"quantizing F16 model to Q4_K_M","digest":"sha256:abc","total":100,"completed":50}
+{"status":"quantizing F16 model to Q4_K_M","digest":"sha256:abc","total":100,"completed":100}
+{"error":"unsupported quantization type q9"}
+`)
+	})
+	defer server.Close()
+
+	stream, err := client.CreateModelStream(context.Background(), &CreateModelRequest{
+		Model:      "mario",
+		From:       "llama3.2",
+		System:     "You are Mario.",
+		Parameters: map[string]interface{}{"temperature": 0.5},
+		Quantize:   "q9",
+	})
+	if err != nil {
+		t.Fatalf("CreateModelStream() error = %v", err)
+	}
+	var received []ModelResponse
+	for response := range stream {
+		received = append(received, response)
+	}
+
+	want := map[string]interface{}{
+		"model":      "mario",
+		"from":       "llama3.2",
+		"system":     "You are Mario.",
+		"parameters": map[string]interface{}{"temperature": 0.5},
+		"quantize":   "q9",
+		"stream":     true,
+	}
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("request body = %v, want %v", body, want)
+	}
+	if len(received) != 3 {
+		t.Fatalf("received %d responses, want 3", len(received))
+	}
+	if got := received[1]; got.Completed != 100 || got.Total != 100 || got.Digest != "sha256:abc" || got.Error != nil {
+		t.Errorf("progress = %+v, want 100 of 100 for sha256:abc", got)
+	}
+	if err := received[2].Error; err == nil || !strings.Contains(err.Error(), "unsupported quantization") {
+		t.Errorf("last response error = %v, want the server's error", err)
+	}
+
+	// CreateModel waits for the result and reports the error.
+	if err := client.CreateModel(context.Background(), &CreateModelRequest{Model: "mario", From: "llama3.2"}); err == nil || !strings.Contains(err.Error(), "unsupported quantization") {
+		t.Errorf("CreateModel() error = %v, want the server's error", err)
+	}
+	if body["stream"] != false {
+		t.Errorf("CreateModel() sent stream = %v, want false", body["stream"])
+	}
+}
+
 func TestCopyModel(t *testing.T) {
 	server, client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
 		var req CopyModelRequest
@@ -357,7 +427,10 @@ func TestDeleteModel(t *testing.T) {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
-		if r.URL.Query().Get("name") == "" {
+		var req struct {
+			Model string `json:"model"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Model == "" {
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
@@ -433,12 +506,11 @@ func TestEmbeddings(t *testing.T) {
 }
 
 func TestListRunningModels(t *testing.T) {
-	expectedModels := []ModelInfo{
-		{Name: "llama3.2:1b", Size: 1000},
-	}
-
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
 	server, client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
-		json.NewEncoder(w).Encode(map[string][]ModelInfo{"models": expectedModels})
+		fmt.Fprintf(w, `{"models":[{"name":"llama3.2:1b","model":"llama3.2:1b","size":4000,"digest":"baf6a787fdff",
+			"details":{"format":"gguf","family":"llama"},"expires_at":%q,"size_vram":3000,"context_length":8192}]}`,
+			expiresAt.Format(time.RFC3339))
 	})
 	defer server.Close()
 
@@ -447,8 +519,27 @@ func TestListRunningModels(t *testing.T) {
 		t.Fatalf("ListRunningModels() error = %v", err)
 	}
 
+	expectedModels := []RunningModel{{
+		Name:          "llama3.2:1b",
+		Model:         "llama3.2:1b",
+		Size:          4000,
+		Digest:        "baf6a787fdff",
+		Details:       ModelDetails{Format: "gguf", Family: "llama"},
+		ExpiresAt:     expiresAt,
+		SizeVRAM:      3000,
+		ContextLength: 8192,
+	}}
 	if !reflect.DeepEqual(models, expectedModels) {
-		t.Errorf("ListRunningModels() got = %v, want %v", models, expectedModels)
+		t.Fatalf("ListRunningModels() got = %+v, want %+v", models, expectedModels)
+	}
+	if got := models[0].VRAMFraction(); got != 0.75 {
+		t.Errorf("VRAMFraction() = %v, want 0.75", got)
+	}
+	if got := models[0].TimeUntilUnload(); got <= 58*time.Minute || got > time.Hour {
+		t.Errorf("TimeUntilUnload() = %v, want about an hour", got)
+	}
+	if got := (RunningModel{ExpiresAt: time.Now().Add(-time.Minute)}).TimeUntilUnload(); got != 0 {
+		t.Errorf("TimeUntilUnload() of an expired model = %v, want 0", got)
 	}
 }
 
@@ -0,0 +1,99 @@
+package ollama
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sort"
+)
+
+// TokenSource returns a bearer token for requests. It is called with
+// refresh set after the server rejected the current token with 401, and the
+// request is then retried once with the new token.
+type TokenSource func(ctx context.Context, refresh bool) (string, error)
+
+// WithHeader sets a header sent with every request
+func WithHeader(key, value string) func(*ClientOptions) {
+	return func(o *ClientOptions) {
+		if o.Headers == nil {
+			o.Headers = make(http.Header)
+		}
+		o.Headers.Set(key, value)
+	}
+}
+
+// WithHeaders adds headers sent with every request
+func WithHeaders(h http.Header) func(*ClientOptions) {
+	return func(o *ClientOptions) {
+		if o.Headers == nil {
+			o.Headers = make(http.Header)
+		}
+		for key, values := range h {
+			for _, v := range values {
+				o.Headers.Add(key, v)
+			}
+		}
+	}
+}
+
+// WithBearerToken authenticates requests with a static bearer token
+func WithBearerToken(token string) func(*ClientOptions) {
+	return func(o *ClientOptions) {
+		o.BearerToken = token
+	}
+}
+
+// WithBasicAuth authenticates requests with HTTP basic auth
+func WithBasicAuth(username, password string) func(*ClientOptions) {
+	return func(o *ClientOptions) {
+		o.BasicAuthUsername = username
+		o.BasicAuthPassword = password
+	}
+}
+
+// WithTokenSource authenticates requests with bearer tokens from ts
+func WithTokenSource(ts TokenSource) func(*ClientOptions) {
+	return func(o *ClientOptions) {
+		o.TokenSource = ts
+	}
+}
+
+// setAuth sets the Authorization header on req. token is the current token
+// from the TokenSource, which takes precedence over static credentials.
+func (c *Client) setAuth(req *http.Request, token string) {
+	switch {
+	case c.opts.TokenSource != nil:
+		req.Header.Set("Authorization", "Bearer "+token)
+	case c.opts.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+c.opts.BearerToken)
+	case c.opts.BasicAuthUsername != "" || c.opts.BasicAuthPassword != "":
+		req.SetBasicAuth(c.opts.BasicAuthUsername, c.opts.BasicAuthPassword)
+	}
+}
+
+// loggedHeaders are headers whose values are safe to log.
+var loggedHeaders = map[string]bool{
+	"Accept":       true,
+	"Content-Type": true,
+	"Traceparent":  true,
+	"User-Agent":   true,
+}
+
+// redactHeaders formats h for logging with the values of all but a few
+// well-known headers redacted.
+func redactHeaders(h http.Header) slog.Attr {
+	keys := make([]string, 0, len(h))
+	for key := range h {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	attrs := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		value := "[redacted]"
+		if loggedHeaders[http.CanonicalHeaderKey(key)] {
+			value = h.Get(key)
+		}
+		attrs = append(attrs, slog.String(key, value))
+	}
+	return slog.Group("headers", attrs...)
+}
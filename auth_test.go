@@ -0,0 +1,134 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/wiseinf/ollama-go/ollamatest"
+)
+
+func TestAuthHeaders(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []ClientOption
+		want http.Header
+	}{
+		{
+			name: "static headers",
+			opts: []ClientOption{WithHeader("X-Team", "ml"), WithHeaders(http.Header{"X-Env": {"prod"}})},
+			want: http.Header{"X-Team": {"ml"}, "X-Env": {"prod"}},
+		},
+		{
+			name: "bearer token",
+			opts: []ClientOption{WithBearerToken("s3cret")},
+			want: http.Header{"Authorization": {"Bearer s3cret"}},
+		},
+		{
+			name: "basic auth",
+			opts: []ClientOption{WithBasicAuth("user", "pass")},
+			want: http.Header{"Authorization": {"Basic dXNlcjpwYXNz"}},
+		},
+		{
+			name: "auth overrides static header",
+			opts: []ClientOption{WithHeader("Authorization", "Bearer old"), WithBearerToken("new")},
+			want: http.Header{"Authorization": {"Bearer new"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got http.Header
+			_, client := newTestServer(t, withHandler("/api/tags", func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Clone()
+				io.WriteString(w, `{"models":[]}`)
+			}), withClientOptions(tt.opts...))
+			if _, err := client.ListModels(context.Background()); err != nil {
+				t.Fatalf("ListModels() error = %v", err)
+			}
+			for key := range tt.want {
+				if got.Get(key) != tt.want.Get(key) {
+					t.Errorf("header %s = %q, want %q", key, got.Get(key), tt.want.Get(key))
+				}
+			}
+		})
+	}
+}
+
+func TestTokenSourceRefresh(t *testing.T) {
+	var mu sync.Mutex
+	var calls []bool
+	source := func(ctx context.Context, refresh bool) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, refresh)
+		if refresh {
+			return "fresh", nil
+		}
+		return "stale", nil
+	}
+	server, client := newTestServer(t, withHandler("/api/tags", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			io.WriteString(w, `{"error":"unauthorized"}`)
+			return
+		}
+		io.WriteString(w, `{"models":[]}`)
+	}), withClientOptions(WithMaxRetries(0), WithTokenSource(source)))
+	if _, err := client.ListModels(context.Background()); err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	if len(calls) != 2 || calls[0] || !calls[1] {
+		t.Errorf("token source calls = %v, want [false true]", calls)
+	}
+	if hits := server.Hits("/api/tags"); hits != 2 {
+		t.Errorf("hits = %d, want 2", hits)
+	}
+}
+
+func TestTokenSourceRetriesOnce(t *testing.T) {
+	refreshes := 0
+	source := func(ctx context.Context, refresh bool) (string, error) {
+		if refresh {
+			refreshes++
+		}
+		return "token", nil
+	}
+	server, client := newTestServer(t, withClientOptions(WithTokenSource(source)))
+	server.Inject("/api/tags", ollamatest.Fault{Times: 10, Status: http.StatusUnauthorized})
+	if _, err := client.ListModels(context.Background()); err == nil {
+		t.Fatal("ListModels() error = nil, want unauthorized")
+	}
+	if refreshes != 1 || server.Hits("/api/tags") != 2 {
+		t.Errorf("refreshes = %d, hits = %d, want 1 and 2", refreshes, server.Hits("/api/tags"))
+	}
+
+	failing := NewClient(WithBaseURL(server.URL), WithTokenSource(func(ctx context.Context, refresh bool) (string, error) {
+		return "", errors.New("no credentials")
+	}))
+	if _, err := failing.ListModels(context.Background()); err == nil || !strings.Contains(err.Error(), "no credentials") {
+		t.Errorf("ListModels() error = %v, want token source error", err)
+	}
+}
+
+func TestAuthHeadersRedactedInLogs(t *testing.T) {
+	logger := &recordingLogger{}
+	_, client := newTestServer(t, withClientOptions(WithLogger(logger), WithDebug(true),
+		WithBearerToken("s3cret"), WithHeader("X-Api-Key", "k3y")))
+
+	if _, err := client.ListModels(context.Background()); err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	got := strings.Join(logger.lines, "\n")
+	if strings.Contains(got, "s3cret") || strings.Contains(got, "k3y") {
+		t.Errorf("log output contains credentials:\n%s", got)
+	}
+	for _, want := range []string{"headers.Authorization=[redacted]", "headers.X-Api-Key=[redacted]", "headers.Content-Type=application/json"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("log output missing %q:\n%s", want, got)
+		}
+	}
+}
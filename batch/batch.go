@@ -0,0 +1,335 @@
+// Package batch runs JSONL files of Ollama requests through a client with
+// bounded concurrency and writes one JSONL result per request.
+//
+// Each input line is a job:
+//
+//	{"id": "q1", "type": "generate", "request": {"model": "llama3.2:1b", "prompt": "Hi"}}
+//	{"id": "q2", "type": "chat", "request": {"model": "llama3.2:1b", "messages": [...]}}
+//	{"id": "q3", "type": "embed", "request": {"model": "all-minilm", "prompt": "Hi"}}
+//
+// The type may be omitted, in which case it is inferred from the request:
+// messages mean chat, a prompt means generate. Results carry the job id, so
+// an interrupted run can be resumed by skipping ids that already succeeded.
+package batch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	ollama "github.com/wiseinf/ollama-go"
+)
+
+// Type is the kind of request a job makes.
+type Type string
+
+const (
+	Generate Type = "generate"
+	Chat     Type = "chat"
+	Embed    Type = "embed"
+)
+
+// Job is a single line of a batch input file.
+type Job struct {
+	ID      string          `json:"id"`
+	Type    Type            `json:"type,omitempty"`
+	Request json.RawMessage `json:"request"`
+}
+
+// Result is a single line of a batch output file.
+type Result struct {
+	ID           string          `json:"id"`
+	Type         Type            `json:"type"`
+	Model        string          `json:"model,omitempty"`
+	LatencyMs    float64         `json:"latency_ms"`
+	PromptTokens int             `json:"prompt_tokens,omitempty"`
+	EvalTokens   int             `json:"eval_tokens,omitempty"`
+	Response     json.RawMessage `json:"response,omitempty"`
+	Error        string          `json:"error,omitempty"`
+}
+
+// Stats summarises a run.
+type Stats struct {
+	Total     int `json:"total"`
+	Skipped   int `json:"skipped"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+}
+
+// Option configures a Runner.
+type Option func(*Runner)
+
+// WithConcurrency sets how many jobs run at once. Defaults to 4.
+func WithConcurrency(n int) Option {
+	return func(r *Runner) {
+		if n > 0 {
+			r.concurrency = n
+		}
+	}
+}
+
+// WithSkip skips jobs whose ids are in ids, typically the result of Completed.
+func WithSkip(ids map[string]bool) Option {
+	return func(r *Runner) {
+		r.skip = ids
+	}
+}
+
+// Runner executes batch jobs through a client.
+type Runner struct {
+	client      ollama.API
+	concurrency int
+	skip        map[string]bool
+}
+
+// NewRunner creates a Runner that sends requests through client, such as
+// an *ollama.Client or an *ollama.Pool.
+func NewRunner(client ollama.API, opts ...Option) *Runner {
+	r := &Runner{
+		client:      client,
+		concurrency: 4,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run reads jobs from in, executes them and writes results to out as they
+// complete. Failed jobs are recorded in out and do not stop the run; a
+// malformed input line does, after in-flight jobs have finished.
+func (r *Runner) Run(ctx context.Context, in io.Reader, out io.Writer) (Stats, error) {
+	var (
+		stats Stats
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		werr  error
+	)
+	enc := json.NewEncoder(out)
+	sem := make(chan struct{}, r.concurrency)
+	seen := make(map[string]bool)
+
+	record := func(res Result) {
+		mu.Lock()
+		defer mu.Unlock()
+		if res.Error != "" {
+			stats.Failed++
+		} else {
+			stats.Succeeded++
+		}
+		if err := enc.Encode(res); err != nil && werr == nil {
+			werr = fmt.Errorf("failed to write result %s: %w", res.ID, err)
+		}
+	}
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	var err error
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var job Job
+		if err = json.Unmarshal(scanner.Bytes(), &job); err != nil {
+			err = fmt.Errorf("line %d: %w", line, err)
+			break
+		}
+		if job.ID == "" {
+			err = fmt.Errorf("line %d: job has no id", line)
+			break
+		}
+		if seen[job.ID] {
+			err = fmt.Errorf("line %d: duplicate job id %q", line, job.ID)
+			break
+		}
+		seen[job.ID] = true
+
+		mu.Lock()
+		stats.Total++
+		if r.skip[job.ID] {
+			stats.Skipped++
+			mu.Unlock()
+			continue
+		}
+		mu.Unlock()
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		if err != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(job Job) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			record(r.execute(ctx, job))
+		}(job)
+	}
+	if err == nil {
+		err = scanner.Err()
+	}
+
+	wg.Wait()
+	if err == nil {
+		err = werr
+	}
+	return stats, err
+}
+
+// execute runs a single job and converts the outcome into a Result.
+func (r *Runner) execute(ctx context.Context, job Job) Result {
+	res := Result{ID: job.ID, Type: job.Type}
+	if res.Type == "" {
+		res.Type = inferType(job.Request)
+	}
+
+	start := time.Now()
+	resp, err := r.send(ctx, &res, job.Request)
+	res.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	if res.Response, err = json.Marshal(resp); err != nil {
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// send decodes the job request for its type, makes the call and fills in
+// the model and token counts on res.
+func (r *Runner) send(ctx context.Context, res *Result, raw json.RawMessage) (interface{}, error) {
+	switch res.Type {
+	case Generate:
+		var req ollama.GenerateRequest
+		if err := json.Unmarshal(raw, &req); err != nil {
+			return nil, fmt.Errorf("invalid generate request: %w", err)
+		}
+		res.Model = req.Model
+		resp, err := r.client.Generate(ctx, &req)
+		if err != nil {
+			return nil, err
+		}
+		res.PromptTokens, res.EvalTokens = resp.PromptEvalCount, resp.EvalCount
+		return resp, nil
+	case Chat:
+		var req ollama.ChatRequest
+		if err := json.Unmarshal(raw, &req); err != nil {
+			return nil, fmt.Errorf("invalid chat request: %w", err)
+		}
+		req.Stream = false
+		res.Model = req.Model
+		resp, err := r.client.Chat(ctx, &req)
+		if err != nil {
+			return nil, err
+		}
+		res.PromptTokens, res.EvalTokens = resp.PromptEvalCount, resp.EvalCount
+		return resp, nil
+	case Embed:
+		var req ollama.EmbeddingRequest
+		if err := json.Unmarshal(raw, &req); err != nil {
+			return nil, fmt.Errorf("invalid embed request: %w", err)
+		}
+		res.Model = req.Model
+		return r.client.Embeddings(ctx, &req)
+	default:
+		return nil, fmt.Errorf("unknown job type %q", res.Type)
+	}
+}
+
+// inferType guesses the job type from the request fields.
+func inferType(raw json.RawMessage) Type {
+	var probe struct {
+		Messages json.RawMessage `json:"messages"`
+	}
+	json.Unmarshal(raw, &probe)
+	if probe.Messages != nil {
+		return Chat
+	}
+	return Generate
+}
+
+// Completed reads a batch output stream and returns the ids of jobs that
+// succeeded. A truncated final line, as left by an interrupted run, is
+// ignored.
+func Completed(r io.Reader) (map[string]bool, error) {
+	done := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var res Result
+		if err := json.Unmarshal(scanner.Bytes(), &res); err != nil {
+			continue
+		}
+		if res.Error == "" {
+			done[res.ID] = true
+		}
+	}
+	return done, scanner.Err()
+}
+
+// RunFile runs the jobs in inPath and appends results to outPath. When
+// resume is true, jobs that already succeeded in outPath are skipped;
+// otherwise outPath is truncated first.
+func RunFile(ctx context.Context, client ollama.API, inPath, outPath string, resume bool, opts ...Option) (Stats, error) {
+	in, err := os.Open(inPath)
+	if err != nil {
+		return Stats{}, err
+	}
+	defer in.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume {
+		flags = os.O_CREATE | os.O_RDWR | os.O_APPEND
+	}
+	out, err := os.OpenFile(outPath, flags, 0o644)
+	if err != nil {
+		return Stats{}, err
+	}
+	defer out.Close()
+
+	if resume {
+		done, err := Completed(out)
+		if err != nil {
+			return Stats{}, fmt.Errorf("failed to read previous results: %w", err)
+		}
+		if err := terminateLastLine(out); err != nil {
+			return Stats{}, err
+		}
+		opts = append(opts, WithSkip(done))
+	}
+
+	stats, err := NewRunner(client, opts...).Run(ctx, in, out)
+	if errors.Is(err, context.Canceled) {
+		return stats, fmt.Errorf("batch interrupted, rerun with resume to continue: %w", err)
+	}
+	return stats, err
+}
+
+// terminateLastLine appends a newline if f does not end with one, so results
+// appended after an interrupted write start on a fresh line.
+func terminateLastLine(f *os.File) error {
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+	last := make([]byte, 1)
+	if _, err := f.ReadAt(last, info.Size()-1); err != nil {
+		return err
+	}
+	if last[0] != '\n' {
+		_, err = f.Write([]byte{'\n'})
+	}
+	return err
+}
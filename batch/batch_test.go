@@ -0,0 +1,196 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	ollama "github.com/wiseinf/ollama-go"
+	"github.com/wiseinf/ollama-go/ollamatest"
+)
+
+const jobs = `{"id": "g1", "type": "generate", "request": {"model": "llama3.2:1b", "prompt": "Hi"}}
+{"id": "c1", "request": {"model": "llama3.2:1b", "messages": [{"role": "user", "content": "Hi"}]}}
+{"id": "e1", "type": "embed", "request": {"model": "all-minilm", "prompt": "Hi"}}
+{"id": "g2", "request": {"model": "llama3.2:1b", "prompt": "Bye"}}
+`
+
+func newTestClient(server *ollamatest.Server) *ollama.Client {
+	return ollama.NewClient(ollama.WithBaseURL(server.URL), ollama.WithMaxRetries(0))
+}
+
+func decodeResults(t *testing.T, data []byte) map[string]Result {
+	t.Helper()
+	results := make(map[string]Result)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var res Result
+		if err := json.Unmarshal([]byte(line), &res); err != nil {
+			t.Fatalf("invalid result line %q: %v", line, err)
+		}
+		results[res.ID] = res
+	}
+	return results
+}
+
+func TestRunPool(t *testing.T) {
+	var urls []string
+	for i := 0; i < 2; i++ {
+		server := ollamatest.NewServer()
+		defer server.Close()
+		urls = append(urls, server.URL)
+	}
+	pool, err := ollama.NewPool(urls, ollama.WithHealthCheckInterval(0))
+	if err != nil {
+		t.Fatalf("NewPool() error = %v", err)
+	}
+	defer pool.Close()
+
+	var out bytes.Buffer
+	stats, err := NewRunner(pool).Run(context.Background(), strings.NewReader(jobs), &out)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if want := (Stats{Total: 4, Succeeded: 4}); stats != want {
+		t.Errorf("Run() stats = %+v, want %+v", stats, want)
+	}
+}
+
+func TestRun(t *testing.T) {
+	server := ollamatest.NewServer()
+	defer server.Close()
+
+	var out bytes.Buffer
+	stats, err := NewRunner(newTestClient(server), WithConcurrency(2)).Run(context.Background(), strings.NewReader(jobs), &out)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if want := (Stats{Total: 4, Succeeded: 4}); stats != want {
+		t.Errorf("Run() stats = %+v, want %+v", stats, want)
+	}
+
+	results := decodeResults(t, out.Bytes())
+	wantTypes := map[string]Type{"g1": Generate, "c1": Chat, "e1": Embed, "g2": Generate}
+	for id, typ := range wantTypes {
+		res, ok := results[id]
+		if !ok {
+			t.Errorf("missing result for %s", id)
+			continue
+		}
+		if res.Type != typ || res.Error != "" || len(res.Response) == 0 {
+			t.Errorf("result %s = %+v, want type %s and a response", id, res, typ)
+		}
+	}
+	if res := results["g1"]; res.PromptTokens != 10 || res.EvalTokens != 20 || res.Model != "llama3.2:1b" {
+		t.Errorf("result g1 = %+v, want model and token counts", res)
+	}
+}
+
+func TestRunBoundsConcurrency(t *testing.T) {
+	server := ollamatest.NewServer()
+	defer server.Close()
+	server.Inject("/api/generate", ollamatest.Fault{Latency: 50 * time.Millisecond, Times: 6})
+
+	var lines []string
+	for _, id := range []string{"1", "2", "3", "4", "5", "6"} {
+		lines = append(lines, `{"id": "`+id+`", "request": {"model": "m", "prompt": "p"}}`)
+	}
+
+	start := time.Now()
+	var out bytes.Buffer
+	if _, err := NewRunner(newTestClient(server), WithConcurrency(3)).Run(context.Background(), strings.NewReader(strings.Join(lines, "\n")), &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Run() took %v, want at least two rounds of 3 concurrent jobs", elapsed)
+	}
+}
+
+func TestRunRecordsFailures(t *testing.T) {
+	server := ollamatest.NewServer()
+	defer server.Close()
+	server.Inject("/api/embeddings", ollamatest.Fault{Status: http.StatusNotFound, ErrorMessage: "model not found"})
+
+	var out bytes.Buffer
+	stats, err := NewRunner(newTestClient(server)).Run(context.Background(), strings.NewReader(jobs), &out)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if stats.Failed != 1 || stats.Succeeded != 3 {
+		t.Errorf("Run() stats = %+v, want 1 failure", stats)
+	}
+	if res := decodeResults(t, out.Bytes())["e1"]; !strings.Contains(res.Error, "model not found") {
+		t.Errorf("result e1 error = %q, want the API error", res.Error)
+	}
+}
+
+func TestRunRejectsMalformedInput(t *testing.T) {
+	tests := map[string]string{
+		"invalid json": `{"id": "1", "request": `,
+		"missing id":   `{"request": {"model": "m", "prompt": "p"}}`,
+		"duplicate id": `{"id": "1", "request": {}}` + "\n" + `{"id": "1", "request": {}}`,
+		"unknown type": `{"id": "1", "type": "rerank", "request": {}}`,
+	}
+
+	server := ollamatest.NewServer()
+	defer server.Close()
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			stats, err := NewRunner(newTestClient(server)).Run(context.Background(), strings.NewReader(input), &out)
+			if err == nil && stats.Failed == 0 {
+				t.Errorf("Run() accepted %q", input)
+			}
+		})
+	}
+}
+
+func TestRunFileResumes(t *testing.T) {
+	server := ollamatest.NewServer()
+	defer server.Close()
+
+	dir := t.TempDir()
+	in := filepath.Join(dir, "jobs.jsonl")
+	out := filepath.Join(dir, "results.jsonl")
+	os.WriteFile(in, []byte(jobs), 0o644)
+
+	// Simulate an interrupted run: g1 succeeded, c1 failed and the write of
+	// e1 was cut short.
+	previous := `{"id":"g1","type":"generate","latency_ms":1}` + "\n" +
+		`{"id":"c1","type":"chat","latency_ms":1,"error":"boom"}` + "\n" +
+		`{"id":"e1","ty`
+	os.WriteFile(out, []byte(previous), 0o644)
+
+	stats, err := RunFile(context.Background(), newTestClient(server), in, out, true)
+	if err != nil {
+		t.Fatalf("RunFile() error = %v", err)
+	}
+	if want := (Stats{Total: 4, Skipped: 1, Succeeded: 3}); stats != want {
+		t.Errorf("RunFile() stats = %+v, want %+v", stats, want)
+	}
+	if got := server.Hits("/api/generate"); got != 1 {
+		t.Errorf("generate calls = %d, want only g2 to rerun", got)
+	}
+
+	f, _ := os.Open(out)
+	defer f.Close()
+	done, err := Completed(f)
+	if err != nil {
+		t.Fatalf("Completed() error = %v", err)
+	}
+	var ids []string
+	for id := range done {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	if got := strings.Join(ids, ","); got != "c1,e1,g1,g2" {
+		t.Errorf("Completed() = %s, want every job", got)
+	}
+}
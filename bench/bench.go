@@ -0,0 +1,259 @@
+// Package bench measures Ollama model performance through the client:
+// time to first token, load time, and prompt and generation throughput,
+// across models and concurrency levels.
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	ollama "github.com/wiseinf/ollama-go"
+	"github.com/wiseinf/ollama-go/internal/stats"
+)
+
+// Config describes a benchmark run.
+type Config struct {
+	// Models to benchmark, one after another.
+	Models []string
+	// Prompts are sent round-robin.
+	Prompts []string
+	// Concurrency levels to run each model at. Defaults to [1].
+	Concurrency []int
+	// Requests per model and concurrency level. Defaults to len(Prompts).
+	Requests int
+	// Options are passed to every generate request.
+	Options map[string]interface{}
+	// Warmup sends one untimed request per model first, so that load time
+	// does not skew the first measurement.
+	Warmup bool
+}
+
+// Sample is the measurement of a single request.
+type Sample struct {
+	TTFT         time.Duration
+	Total        time.Duration
+	Load         time.Duration
+	PromptTokens int
+	EvalTokens   int
+	// PromptRate and EvalRate are tokens per second as reported by the server.
+	PromptRate float64
+	EvalRate   float64
+	Err        error
+}
+
+// Percentiles summarises a distribution.
+type Percentiles struct {
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P99  float64 `json:"p99"`
+}
+
+// Result summarises the samples for one model at one concurrency level.
+// Durations are in milliseconds and rates in tokens per second.
+type Result struct {
+	Model       string      `json:"model"`
+	Concurrency int         `json:"concurrency"`
+	Requests    int         `json:"requests"`
+	Errors      int         `json:"errors"`
+	TTFTMs      Percentiles `json:"ttft_ms"`
+	LatencyMs   Percentiles `json:"latency_ms"`
+	LoadMs      Percentiles `json:"load_ms"`
+	PromptRate  Percentiles `json:"prompt_tokens_per_sec"`
+	EvalRate    Percentiles `json:"eval_tokens_per_sec"`
+	// Throughput is the aggregate generated tokens per second of wall time.
+	Throughput float64 `json:"throughput_tokens_per_sec"`
+	// LastError is the most recent request error, if any.
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Report is the outcome of a benchmark run.
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// Run executes the benchmark described by cfg against client, such as an
+// *ollama.Client or an *ollama.Pool.
+func Run(ctx context.Context, client ollama.API, cfg Config) (*Report, error) {
+	if len(cfg.Models) == 0 {
+		return nil, errors.New("bench: no models configured")
+	}
+	if len(cfg.Prompts) == 0 {
+		return nil, errors.New("bench: no prompts configured")
+	}
+	levels := cfg.Concurrency
+	if len(levels) == 0 {
+		levels = []int{1}
+	}
+	requests := cfg.Requests
+	if requests <= 0 {
+		requests = len(cfg.Prompts)
+	}
+
+	report := &Report{}
+	for _, model := range cfg.Models {
+		if cfg.Warmup {
+			if s := measure(ctx, client, model, cfg.Prompts[0], cfg.Options); s.Err != nil {
+				return report, fmt.Errorf("bench: warmup of %s failed: %w", model, s.Err)
+			}
+		}
+		for _, level := range levels {
+			if level <= 0 {
+				return report, fmt.Errorf("bench: invalid concurrency %d", level)
+			}
+			start := time.Now()
+			samples := runLevel(ctx, client, model, level, requests, cfg)
+			report.Results = append(report.Results, summarize(model, level, samples, time.Since(start)))
+			if err := ctx.Err(); err != nil {
+				return report, err
+			}
+		}
+	}
+	return report, nil
+}
+
+// runLevel sends requests prompts to model, level at a time.
+func runLevel(ctx context.Context, client ollama.API, model string, level, requests int, cfg Config) []Sample {
+	samples := make([]Sample, requests)
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < level; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				samples[i] = measure(ctx, client, model, cfg.Prompts[i%len(cfg.Prompts)], cfg.Options)
+			}
+		}()
+	}
+	for i := 0; i < requests && ctx.Err() == nil; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return samples
+}
+
+// measure streams a single generation and records its timings.
+func measure(ctx context.Context, client ollama.API, model, prompt string, options map[string]interface{}) Sample {
+	var s Sample
+	start := time.Now()
+	stream, err := client.GenerateStream(ctx, &ollama.GenerateRequest{
+		Model:   model,
+		Prompt:  prompt,
+		Options: options,
+	})
+	if err != nil {
+		s.Err = err
+		return s
+	}
+
+	for item := range stream {
+		if item.Error != nil {
+			s.Err = item.Error
+			continue
+		}
+		resp := item.GenerateResponse
+		if s.TTFT == 0 && resp.Response != "" {
+			s.TTFT = time.Since(start)
+		}
+		if resp.Done {
+			usage := resp.Usage()
+			s.Load = usage.LoadDuration
+			s.PromptTokens = usage.PromptTokens
+			s.EvalTokens = usage.EvalTokens
+			s.PromptRate = usage.PromptTokensPerSecond()
+			s.EvalRate = usage.EvalTokensPerSecond()
+		}
+	}
+	s.Total = time.Since(start)
+	return s
+}
+
+func summarize(model string, level int, samples []Sample, wall time.Duration) Result {
+	res := Result{Model: model, Concurrency: level}
+	var ttft, latency, load, promptRate, evalRate []float64
+	var evalTokens int
+	for _, s := range samples {
+		if s.Err == nil && s.Total == 0 {
+			// Never sent because the run was cancelled.
+			continue
+		}
+		res.Requests++
+		if s.Err != nil {
+			res.Errors++
+			res.LastError = s.Err.Error()
+			continue
+		}
+		ttft = append(ttft, ms(s.TTFT))
+		latency = append(latency, ms(s.Total))
+		load = append(load, ms(s.Load))
+		promptRate = append(promptRate, s.PromptRate)
+		evalRate = append(evalRate, s.EvalRate)
+		evalTokens += s.EvalTokens
+	}
+
+	res.TTFTMs = percentiles(ttft)
+	res.LatencyMs = percentiles(latency)
+	res.LoadMs = percentiles(load)
+	res.PromptRate = percentiles(promptRate)
+	res.EvalRate = percentiles(evalRate)
+	if wall > 0 {
+		res.Throughput = float64(evalTokens) / wall.Seconds()
+	}
+	return res
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// percentiles computes the mean and nearest-rank percentiles of values.
+func percentiles(values []float64) Percentiles {
+	if len(values) == 0 {
+		return Percentiles{}
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	rank := func(p float64) float64 {
+		return sorted[stats.NearestRank(len(sorted), p)]
+	}
+	return Percentiles{
+		Mean: sum / float64(len(sorted)),
+		P50:  rank(0.50),
+		P90:  rank(0.90),
+		P99:  rank(0.99),
+	}
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteTable writes the report as an aligned text table.
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "MODEL\tCONC\tREQS\tERRS\tTTFT p50\tTTFT p99\tLOAD p50\tPROMPT t/s p50\tEVAL t/s p50\tEVAL t/s p90\tTHROUGHPUT t/s\t")
+	for _, res := range r.Results {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.0fms\t%.0fms\t%.0fms\t%.1f\t%.1f\t%.1f\t%.1f\t\n",
+			res.Model, res.Concurrency, res.Requests, res.Errors,
+			res.TTFTMs.P50, res.TTFTMs.P99, res.LoadMs.P50,
+			res.PromptRate.P50, res.EvalRate.P50, res.EvalRate.P90, res.Throughput)
+	}
+	return tw.Flush()
+}
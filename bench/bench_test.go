@@ -0,0 +1,125 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	ollama "github.com/wiseinf/ollama-go"
+	"github.com/wiseinf/ollama-go/ollamatest"
+)
+
+func TestRun(t *testing.T) {
+	server := ollamatest.NewServer()
+	defer server.Close()
+	server.Reply = "one two three"
+	server.Inject("/api/generate", ollamatest.Fault{Drip: 20 * time.Millisecond, Times: 100})
+
+	client := ollama.NewClient(ollama.WithBaseURL(server.URL))
+	report, err := Run(context.Background(), client, Config{
+		Models:      []string{"llama3.2:1b", "qwen2.5:0.5b"},
+		Prompts:     []string{"a", "b"},
+		Concurrency: []int{1, 2},
+		Requests:    4,
+		Warmup:      true,
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if len(report.Results) != 4 {
+		t.Fatalf("Run() results = %d, want 2 models x 2 levels", len(report.Results))
+	}
+	if got := server.Hits("/api/generate"); got != 2*2*4+2 {
+		t.Errorf("generate calls = %d, want 18", got)
+	}
+	for _, res := range report.Results {
+		if res.Requests != 4 || res.Errors != 0 {
+			t.Errorf("%s@%d requests = %d, errors = %d", res.Model, res.Concurrency, res.Requests, res.Errors)
+		}
+		// The fake server reports 20 tokens in 200ms and 10 prompt tokens in 100ms.
+		if res.EvalRate.P50 != 100 || res.PromptRate.P50 != 100 {
+			t.Errorf("%s@%d rates = %v / %v, want 100 t/s", res.Model, res.Concurrency, res.PromptRate.P50, res.EvalRate.P50)
+		}
+		if res.TTFTMs.P50 < 20 || res.TTFTMs.P50 > res.LatencyMs.P50 {
+			t.Errorf("%s@%d ttft = %vms, latency = %vms", res.Model, res.Concurrency, res.TTFTMs.P50, res.LatencyMs.P50)
+		}
+		if res.LoadMs.P50 != 100 {
+			t.Errorf("%s@%d load = %vms, want 100ms", res.Model, res.Concurrency, res.LoadMs.P50)
+		}
+	}
+
+	var table bytes.Buffer
+	if err := report.WriteTable(&table); err != nil {
+		t.Fatalf("WriteTable() error = %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(table.String()), "\n"); len(lines) != 5 {
+		t.Errorf("WriteTable() wrote %d lines, want header and 4 rows:\n%s", len(lines), table.String())
+	}
+
+	var out bytes.Buffer
+	if err := report.WriteJSON(&out); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil || len(decoded.Results) != 4 {
+		t.Errorf("WriteJSON() output does not round-trip: %v", err)
+	}
+}
+
+func TestRunCountsErrors(t *testing.T) {
+	server := ollamatest.NewServer()
+	defer server.Close()
+	server.Inject("/api/generate", ollamatest.Fault{Status: http.StatusBadRequest, ErrorMessage: "bad prompt", Times: 2})
+
+	client := ollama.NewClient(ollama.WithBaseURL(server.URL))
+	report, err := Run(context.Background(), client, Config{Models: []string{"m"}, Prompts: []string{"a"}, Requests: 3})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	res := report.Results[0]
+	if res.Requests != 3 || res.Errors != 2 || !strings.Contains(res.LastError, "bad prompt") {
+		t.Errorf("Run() result = %+v, want 2 of 3 requests failed", res)
+	}
+}
+
+func TestRunPool(t *testing.T) {
+	var urls []string
+	for i := 0; i < 2; i++ {
+		server := ollamatest.NewServer()
+		defer server.Close()
+		urls = append(urls, server.URL)
+	}
+	pool, err := ollama.NewPool(urls, ollama.WithHealthCheckInterval(0))
+	if err != nil {
+		t.Fatalf("NewPool() error = %v", err)
+	}
+	defer pool.Close()
+
+	report, err := Run(context.Background(), pool, Config{Models: []string{"m"}, Prompts: []string{"a"}, Requests: 4})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if res := report.Results[0]; res.Requests != 4 || res.Errors != 0 {
+		t.Errorf("Run() result = %+v, want 4 requests without errors", res)
+	}
+}
+
+func TestPercentiles(t *testing.T) {
+	values := make([]float64, 100)
+	for i := range values {
+		values[i] = float64(100 - i)
+	}
+	got := percentiles(values)
+	want := Percentiles{Mean: 50.5, P50: 50, P90: 90, P99: 99}
+	if got != want {
+		t.Errorf("percentiles() = %+v, want %+v", got, want)
+	}
+	if got := percentiles(nil); got != (Percentiles{}) {
+		t.Errorf("percentiles(nil) = %+v, want zero", got)
+	}
+}
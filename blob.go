@@ -0,0 +1,185 @@
+package ollama
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// BlobProgress is called as a blob is uploaded with the number of bytes
+// sent so far and the size of the blob.
+type BlobProgress func(sent, total int64)
+
+// uploadBody is a request body sent as-is rather than encoded as JSON.
+type uploadBody struct {
+	// open returns the body from its start. It is called for every attempt,
+	// and the body is closed once the attempt is over.
+	open func() (io.ReadCloser, error)
+	size int64
+}
+
+// progressInterval is the least time between progress reports of an upload.
+const progressInterval = 100 * time.Millisecond
+
+// progressReader reports the bytes read through it, at most once every
+// progressInterval and always once the last byte has been read.
+type progressReader struct {
+	r        io.Reader
+	sent     int64
+	total    int64
+	progress BlobProgress
+	reported time.Time
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.sent += int64(n)
+		if p.sent == p.total || time.Since(p.reported) >= progressInterval {
+			p.reported = time.Now()
+			p.progress(p.sent, p.total)
+		}
+	}
+	return n, err
+}
+
+// BlobExists reports whether the server has the blob with the given
+// digest, e.g. "sha256:29fdb92e57cf...".
+func (c *Client) BlobExists(ctx context.Context, digest string) (bool, error) {
+	call := newCall("HEAD", "/api/blobs/"+digest, nil, false)
+	resp, err := c.do(ctx, call)
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	resp.Body.Close()
+	return true, nil
+}
+
+// PushBlob uploads the contents of r as a blob unless the server already
+// has it, and returns its digest. The SHA-256 digest is computed by
+// streaming r, so blobs are never held in memory; readers that cannot seek
+// are buffered in a temporary file so that they can be sent after the
+// digest is known. progress may be nil. The upload is not bound by the
+// client's timeout, only by ctx.
+func (c *Client) PushBlob(ctx context.Context, r io.Reader, progress BlobProgress) (string, error) {
+	rs, ok := r.(io.ReadSeeker)
+	if !ok {
+		tmp, err := os.CreateTemp("", "ollama-blob-*")
+		if err != nil {
+			return "", fmt.Errorf("failed to buffer blob: %w", err)
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		if _, err := io.Copy(tmp, r); err != nil {
+			return "", fmt.Errorf("failed to buffer blob: %w", err)
+		}
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+		rs = tmp
+	}
+	return c.pushBlob(ctx, rs, progress)
+}
+
+// PushBlobFile uploads a local file, e.g. a GGUF model, as a blob unless
+// the server already has it, and returns its digest.
+func (c *Client) PushBlobFile(ctx context.Context, path string, progress BlobProgress) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return c.pushBlob(ctx, f, progress)
+}
+
+func (c *Client) pushBlob(ctx context.Context, rs io.ReadSeeker, progress BlobProgress) (string, error) {
+	start, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	size, err := io.Copy(h, rs)
+	if err != nil {
+		return "", fmt.Errorf("failed to read blob: %w", err)
+	}
+	digest := "sha256:" + hex.EncodeToString(h.Sum(nil))
+
+	exists, err := c.BlobExists(ctx, digest)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		if progress != nil {
+			progress(size, size)
+		}
+		return digest, nil
+	}
+
+	body := &uploadBody{size: size, open: func() (io.ReadCloser, error) {
+		if _, err := rs.Seek(start, io.SeekStart); err != nil {
+			return nil, err
+		}
+		r := io.LimitReader(rs, size)
+		if progress == nil {
+			return io.NopCloser(r), nil
+		}
+		return io.NopCloser(&progressReader{r: r, total: size, progress: progress}), nil
+	}}
+	call := newCall("POST", "/api/blobs/"+digest, body, false)
+	resp, err := c.do(ctx, call)
+	if err != nil {
+		return "", fmt.Errorf("failed to push blob: %w", err)
+	}
+	resp.Body.Close()
+	return digest, nil
+}
+
+// uploadLocalFiles pushes the blobs of req.LocalFiles and
+// req.LocalAdapters and returns a copy of req that refers to them by
+// digest. progress is called with the upload progress of each file.
+func (c *Client) uploadLocalFiles(ctx context.Context, req *CreateModelRequest, progress func(ModelResponse)) (*CreateModelRequest, error) {
+	r := *req
+	upload := func(local map[string]string, remote map[string]string) (map[string]string, error) {
+		if len(local) == 0 {
+			return remote, nil
+		}
+		merged := make(map[string]string, len(remote)+len(local))
+		for name, digest := range remote {
+			merged[name] = digest
+		}
+		for name, path := range local {
+			var report BlobProgress
+			if progress != nil {
+				status := "uploading " + name
+				report = func(sent, total int64) {
+					progress(ModelResponse{Status: status, Total: total, Completed: sent})
+				}
+			}
+			digest, err := c.PushBlobFile(ctx, path, report)
+			if err != nil {
+				return nil, fmt.Errorf("failed to upload %s: %w", name, err)
+			}
+			merged[name] = digest
+		}
+		return merged, nil
+	}
+
+	var err error
+	if r.Files, err = upload(req.LocalFiles, req.Files); err != nil {
+		return nil, err
+	}
+	if r.Adapters, err = upload(req.LocalAdapters, req.Adapters); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
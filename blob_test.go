@@ -0,0 +1,266 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/wiseinf/ollama-go/ollamatest"
+)
+
+// uploads returns the number of blobs uploaded to server.
+func uploads(server *ollamatest.Server) int {
+	n := 0
+	for _, count := range server.Blobs() {
+		n += count
+	}
+	return n
+}
+
+// createRequests decodes the create requests server received.
+func createRequests(t *testing.T, server *ollamatest.Server) []map[string]interface{} {
+	t.Helper()
+	var reqs []map[string]interface{}
+	for _, body := range server.Bodies("/api/create") {
+		var req map[string]interface{}
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatalf("invalid create request %s: %v", body, err)
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs
+}
+
+func TestPushBlob(t *testing.T) {
+	server, client := newTestServer(t)
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("GGUF"), 50000)
+	sum := sha256.Sum256(data)
+	want := "sha256:" + hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name        string
+		r           io.Reader
+		wantUploads int
+	}{
+		{"seekable", bytes.NewReader(data), 1},
+		{"already present", bytes.NewReader(data), 1},
+		{"stream", io.MultiReader(bytes.NewReader([]byte("ab")), strings.NewReader("cd")), 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var last, total int64
+			digest, err := client.PushBlob(ctx, tt.r, func(sent, size int64) {
+				if sent < last {
+					t.Errorf("progress went back from %d to %d", last, sent)
+				}
+				last, total = sent, size
+			})
+			if err != nil {
+				t.Fatalf("PushBlob() error = %v", err)
+			}
+			if got := uploads(server); got != tt.wantUploads {
+				t.Errorf("uploads = %d, want %d", got, tt.wantUploads)
+			}
+			if last != total || total == 0 {
+				t.Errorf("progress ended at %d of %d", last, total)
+			}
+			if tt.name != "stream" && digest != want {
+				t.Errorf("digest = %s, want %s", digest, want)
+			}
+			if ok, err := client.BlobExists(ctx, digest); err != nil || !ok {
+				t.Errorf("BlobExists(%s) = %v, %v, want true", digest, ok, err)
+			}
+		})
+	}
+
+	if ok, err := client.BlobExists(ctx, "sha256:0000"); err != nil || ok {
+		t.Errorf("BlobExists(missing) = %v, %v, want false", ok, err)
+	}
+}
+
+// slowUpload delays reads once it has been rewound for an upload, so that
+// computing the digest is fast but sending the blob is not.
+type slowUpload struct {
+	io.ReadSeeker
+	delay     time.Duration
+	uploading bool
+}
+
+func (s *slowUpload) Seek(offset int64, whence int) (int64, error) {
+	s.uploading = whence == io.SeekStart
+	return s.ReadSeeker.Seek(offset, whence)
+}
+
+func (s *slowUpload) Read(p []byte) (int, error) {
+	if s.uploading {
+		time.Sleep(s.delay)
+	}
+	return s.ReadSeeker.Read(p)
+}
+
+func TestPushBlobIgnoresClientTimeout(t *testing.T) {
+	_, client := newTestServer(t, withClientOptions(WithTimeout(50*time.Millisecond), WithMaxRetries(0)))
+
+	blob := &slowUpload{ReadSeeker: strings.NewReader("weights"), delay: 150 * time.Millisecond}
+	if _, err := client.PushBlob(context.Background(), blob, nil); err != nil {
+		t.Fatalf("PushBlob() error = %v, want the upload to outlast the client timeout", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	blob = &slowUpload{ReadSeeker: strings.NewReader("more weights"), delay: 150 * time.Millisecond}
+	if _, err := client.PushBlob(ctx, blob, nil); err == nil {
+		t.Error("PushBlob() error = nil, want the context deadline to bound the upload")
+	}
+}
+
+func TestProgressReaderThrottled(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 4096)
+	var calls int
+	var last int64
+	r := &progressReader{r: iotest.OneByteReader(bytes.NewReader(data)), total: int64(len(data)), progress: func(sent, total int64) {
+		calls++
+		last = sent
+	}}
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	if last != int64(len(data)) {
+		t.Errorf("last progress = %d, want %d", last, len(data))
+	}
+	if calls > 10 {
+		t.Errorf("progress called %d times for %d reads, want it throttled", calls, len(data))
+	}
+}
+
+func TestCreateModelLocalFiles(t *testing.T) {
+	server, client := newTestServer(t)
+	dir := t.TempDir()
+	model := filepath.Join(dir, "model.gguf")
+	adapter := filepath.Join(dir, "adapter.gguf")
+	os.WriteFile(model, []byte("model weights"), 0o644)
+	os.WriteFile(adapter, []byte("adapter weights"), 0o644)
+
+	req := &CreateModelRequest{
+		Model:         "custom",
+		Files:         map[string]string{"tokenizer.json": "sha256:abc"},
+		LocalFiles:    map[string]string{"model.gguf": model},
+		LocalAdapters: map[string]string{"adapter.gguf": adapter},
+	}
+	stream, err := client.CreateModelStream(context.Background(), req)
+	if err != nil {
+		t.Fatalf("CreateModelStream() error = %v", err)
+	}
+	var statuses []string
+	for p := range stream {
+		if p.Error != nil {
+			t.Fatalf("stream error = %v", p.Error)
+		}
+		if len(statuses) == 0 || statuses[len(statuses)-1] != p.Status {
+			statuses = append(statuses, p.Status)
+		}
+	}
+	if got, want := strings.Join(statuses, ", "), "uploading model.gguf, uploading adapter.gguf, pulling manifest, writing manifest, success"; got != want {
+		t.Errorf("statuses = %s, want %s", got, want)
+	}
+
+	created := createRequests(t, server)
+	if uploads(server) != 2 || len(created) != 1 {
+		t.Fatalf("uploads = %d, creates = %d, want 2 and 1", uploads(server), len(created))
+	}
+	files := created[0]["files"].(map[string]interface{})
+	adapters := created[0]["adapters"].(map[string]interface{})
+	blobs := server.Blobs()
+	if files["tokenizer.json"] != "sha256:abc" || blobs[files["model.gguf"].(string)] == 0 || blobs[adapters["adapter.gguf"].(string)] == 0 {
+		t.Errorf("create request files = %v, adapters = %v", files, adapters)
+	}
+	if len(req.Files) != 1 {
+		t.Errorf("request files changed to %v", req.Files)
+	}
+
+	// CreateModel uploads too, skipping blobs the server has.
+	if err := client.CreateModel(context.Background(), req); err != nil {
+		t.Fatalf("CreateModel() error = %v", err)
+	}
+	if got, creates := uploads(server), len(createRequests(t, server)); got != 2 || creates != 2 {
+		t.Errorf("uploads = %d, creates = %d, want 2 and 2", got, creates)
+	}
+
+	req.LocalFiles["missing.gguf"] = filepath.Join(dir, "missing.gguf")
+	if err := client.CreateModel(context.Background(), req); err == nil || !strings.Contains(err.Error(), "missing.gguf") {
+		t.Errorf("CreateModel() error = %v, want the missing file", err)
+	}
+}
+
+// closeRecorder records whether it was closed.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestUploadClosedOnBadRequest(t *testing.T) {
+	breaker, clock := newTestBreaker(WithFailureThreshold(1), WithCoolDown(time.Minute))
+	client := NewClient(WithBaseURL("http://bad host"), WithMaxRetries(0), WithCircuitBreaker(breaker), WithLogger(&recordingLogger{}))
+	key := breaker.key(client.baseURL, "")
+	breaker.record(key, false)
+	clock.Advance(time.Minute)
+
+	var bodies []*closeRecorder
+	body := &uploadBody{size: 4, open: func() (io.ReadCloser, error) {
+		r := &closeRecorder{Reader: strings.NewReader("GGUF")}
+		bodies = append(bodies, r)
+		return r, nil
+	}}
+	if _, err := client.do(context.Background(), newCall("POST", "/api/blobs/sha256:0000", body, false)); err == nil {
+		t.Fatal("do() succeeded with an invalid base URL")
+	}
+	if len(bodies) != 1 || !bodies[0].closed {
+		t.Errorf("upload bodies = %+v, want the body closed", bodies)
+	}
+	// The half-open probe is released, so the next call may probe.
+	if _, err := breaker.allow(key); err != nil {
+		t.Errorf("allow() error = %v after the failed attempt", err)
+	}
+}
+
+func TestCreateModelStreamAbandoned(t *testing.T) {
+	_, client := newTestServer(t)
+	path := filepath.Join(t.TempDir(), "model.gguf")
+	os.WriteFile(path, bytes.Repeat([]byte("GGUF"), 1<<20), 0o644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := client.CreateModelStream(ctx, &CreateModelRequest{Model: "custom", LocalFiles: map[string]string{"model.gguf": path}}); err != nil {
+		t.Fatalf("CreateModelStream() error = %v", err)
+	}
+	// The stream is never read; cancelling must still end the upload.
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		buf := make([]byte, 1<<20)
+		stacks := string(buf[:runtime.Stack(buf, true)])
+		if !strings.Contains(stacks, "(*Client).CreateModelStream.func") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("CreateModelStream goroutine still running after cancel:\n%s", stacks)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
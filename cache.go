@@ -0,0 +1,440 @@
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheStore stores cached response bodies by key.
+type CacheStore interface {
+	// Get returns the value stored for key, if it has not expired.
+	Get(key string) ([]byte, bool)
+	// Set stores value for key. A zero ttl means it does not expire.
+	Set(key string, value []byte, ttl time.Duration) error
+}
+
+// LRUCache is an in-memory CacheStore that evicts the least recently used
+// entries beyond its capacity.
+type LRUCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+	now      func() time.Time
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewLRUCache creates an LRUCache holding at most capacity entries.
+func NewLRUCache(capacity int) *LRUCache {
+	return &LRUCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// Get implements CacheStore.
+func (c *LRUCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry)
+	if !e.expires.IsZero() && c.now().After(e.expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return e.value, true
+}
+
+// Set implements CacheStore.
+func (c *LRUCache) Set(key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var expires time.Time
+	if ttl > 0 {
+		expires = c.now().Add(ttl)
+	}
+	if el, ok := c.entries[key]; ok {
+		el.Value = &lruEntry{key: key, value: value, expires: expires}
+		c.order.MoveToFront(el)
+		return nil
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value, expires: expires})
+	for c.capacity > 0 && c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+	return nil
+}
+
+// Len returns the number of entries, including expired ones not yet
+// evicted.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// DiskCache is a CacheStore keeping one file per entry in a directory, so
+// cached responses survive restarts.
+type DiskCache struct {
+	dir string
+	now func() time.Time
+}
+
+// NewDiskCache creates a DiskCache in dir, creating it if needed.
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &DiskCache{dir: dir, now: time.Now}, nil
+}
+
+func (c *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
+
+// Get implements CacheStore. Entries start with a line holding their
+// expiry time in Unix nanoseconds, or 0.
+func (c *DiskCache) Get(key string) ([]byte, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	header, value, ok := bytes.Cut(data, []byte("\n"))
+	if !ok {
+		return nil, false
+	}
+	expires, err := strconv.ParseInt(string(header), 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	if expires > 0 && c.now().UnixNano() > expires {
+		os.Remove(c.path(key))
+		return nil, false
+	}
+	return value, true
+}
+
+// Set implements CacheStore. Entries are written to a temporary file and
+// renamed so readers never see a partial entry.
+func (c *DiskCache) Set(key string, value []byte, ttl time.Duration) error {
+	var expires int64
+	if ttl > 0 {
+		expires = c.now().Add(ttl).UnixNano()
+	}
+	f, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "%d\n", expires)
+	w.Write(value)
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), c.path(key))
+}
+
+// CacheOptions configures a Cache
+type CacheOptions struct {
+	// TTL is how long responses are kept. Zero keeps them until evicted.
+	TTL time.Duration
+	// Force caches generations even when they are not deterministic.
+	Force bool
+	// DigestTTL is how long model digests are reused before they are looked
+	// up again. Responses are keyed on the model digest so that updating a
+	// model invalidates its cached responses.
+	DigestTTL time.Duration
+}
+
+// CacheOption is a function that modifies the cache options
+type CacheOption func(*CacheOptions)
+
+// WithCacheTTL sets how long responses are cached
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(o *CacheOptions) {
+		o.TTL = ttl
+	}
+}
+
+// WithForceCache caches all generations, deterministic or not
+func WithForceCache(force bool) CacheOption {
+	return func(o *CacheOptions) {
+		o.Force = force
+	}
+}
+
+// WithDigestTTL sets how long model digests are reused
+func WithDigestTTL(ttl time.Duration) CacheOption {
+	return func(o *CacheOptions) {
+		o.DigestTTL = ttl
+	}
+}
+
+type forceCacheKey struct{}
+
+// ContextWithForceCache returns a context whose calls are cached even when
+// they are not deterministic.
+func ContextWithForceCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceCacheKey{}, true)
+}
+
+// CacheStats counts cache lookups.
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+}
+
+// Cache answers repeated deterministic Generate, Chat and Embeddings calls
+// from a CacheStore. Add it to a client with WithMiddleware(cache.Middleware()).
+//
+// Calls are keyed on the endpoint, the model digest and the canonical
+// request JSON. Generations are only cached when they are deterministic,
+// that is when the seed option is set or the temperature option is 0,
+// unless forced. Embeddings are always cached. Cached streaming calls are
+// replayed as a stream.
+type Cache struct {
+	store CacheStore
+	opts  *CacheOptions
+
+	mu        sync.Mutex
+	digests   map[string]string
+	refreshed time.Time
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// NewCache creates a Cache backed by store.
+func NewCache(store CacheStore, options ...CacheOption) *Cache {
+	opts := &CacheOptions{DigestTTL: time.Minute}
+	for _, opt := range options {
+		opt(opts)
+	}
+	return &Cache{store: store, opts: opts}
+}
+
+// Stats returns the number of hits and misses.
+func (c *Cache) Stats() CacheStats {
+	return CacheStats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+}
+
+// cacheableEndpoints are the endpoints whose responses may be cached.
+var cacheableEndpoints = map[string]bool{
+	"/api/generate":   true,
+	"/api/chat":       true,
+	"/api/embeddings": true,
+	"/api/embed":      true,
+}
+
+// Middleware returns the middleware that serves and fills the cache.
+func (c *Cache) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (*http.Response, error) {
+			if !cacheableEndpoints[call.Endpoint] {
+				return next(ctx, call)
+			}
+			req, ok := canonicalRequest(call.Request)
+			if !ok || !c.cacheable(ctx, call.Endpoint, req) {
+				return next(ctx, call)
+			}
+			digest, err := c.digest(ctx, next, call)
+			if err != nil {
+				return next(ctx, call)
+			}
+			key := cacheKey(call.Endpoint, digest, req)
+
+			if body, ok := c.store.Get(key); ok {
+				c.hits.Add(1)
+				call.StatusCode = http.StatusOK
+				call.Cached = true
+				return cachedResponse(body, call.Stream), nil
+			}
+			c.misses.Add(1)
+
+			resp, err := next(ctx, call)
+			if err != nil {
+				return nil, err
+			}
+			body := &recordingBody{ReadCloser: resp.Body}
+			if !call.Stream {
+				// A JSON decoder may stop before EOF, so read the whole
+				// response up front.
+				data, err := io.ReadAll(body)
+				resp.Body.Close()
+				if err != nil {
+					return nil, err
+				}
+				resp.Body = io.NopCloser(bytes.NewReader(data))
+			} else {
+				resp.Body = body
+			}
+			call.OnEnd(func(call *Call) {
+				if call.Err == nil && body.complete {
+					c.store.Set(key, body.buf.Bytes(), c.opts.TTL)
+				}
+			})
+			return resp, nil
+		}
+	}
+}
+
+// cacheable reports whether a request may be answered from the cache.
+func (c *Cache) cacheable(ctx context.Context, endpoint string, req map[string]interface{}) bool {
+	if endpoint == "/api/embeddings" || endpoint == "/api/embed" {
+		return true
+	}
+	// A generate request without a prompt loads or unloads the model.
+	if prompt, _ := req["prompt"].(string); endpoint == "/api/generate" && prompt == "" {
+		return false
+	}
+	if c.opts.Force || ctx.Value(forceCacheKey{}) != nil {
+		return true
+	}
+	options, _ := req["options"].(map[string]interface{})
+	if seed, ok := options["seed"]; ok && seed != nil {
+		return true
+	}
+	temperature, ok := options["temperature"].(float64)
+	return ok && temperature == 0
+}
+
+// digest returns the digest of the model call names. The local models are
+// listed through next, as a call of its own, when the cached list is stale;
+// a model missing from a fresh list is reported as not found.
+func (c *Cache) digest(ctx context.Context, next Handler, call *Call) (string, error) {
+	model := call.Model()
+	name := normalizeModelName(model)
+	c.mu.Lock()
+	d, ok := c.digests[name]
+	fresh := c.digests != nil && time.Since(c.refreshed) < c.opts.DigestTTL
+	c.mu.Unlock()
+	if !fresh {
+		digests, err := c.listDigests(ctx, next, call)
+		if err != nil {
+			return "", err
+		}
+		d, ok = digests[name]
+	}
+	if !ok {
+		return "", fmt.Errorf("model %s not found", model)
+	}
+	return d, nil
+}
+
+// listDigests lists the local models and caches their digests.
+func (c *Cache) listDigests(ctx context.Context, next Handler, call *Call) (digests map[string]string, err error) {
+	list := call.subCall("GET", "/api/tags", nil)
+	defer func() { list.end(err) }()
+
+	resp, err := next(ctx, list)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var tags struct {
+		Models []ListedModel `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, err
+	}
+	list.Result = &tags
+
+	digests = make(map[string]string, len(tags.Models))
+	for _, m := range tags.Models {
+		digests[m.Name] = m.Digest
+	}
+	c.mu.Lock()
+	c.digests, c.refreshed = digests, time.Now()
+	c.mu.Unlock()
+	return digests, nil
+}
+
+// canonicalRequest decodes a request struct into generic JSON values so it
+// can be inspected and re-encoded with sorted keys.
+func canonicalRequest(request interface{}) (map[string]interface{}, bool) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, false
+	}
+	var req map[string]interface{}
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, false
+	}
+	return req, true
+}
+
+// cacheKey hashes the parts of a call that determine its response.
+// keep_alive only affects the server, so it is left out.
+func cacheKey(endpoint, digest string, req map[string]interface{}) string {
+	delete(req, "keep_alive")
+	data, _ := json.Marshal(req)
+	sum := sha256.Sum256([]byte(endpoint + "\n" + digest + "\n" + string(data)))
+	return hex.EncodeToString(sum[:])
+}
+
+// cachedResponse builds the response for a cache hit.
+func cachedResponse(body []byte, stream bool) *http.Response {
+	contentType := "application/json"
+	if stream {
+		contentType = "application/x-ndjson"
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": {contentType}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+}
+
+// recordingBody keeps a copy of a response body and notes whether it was
+// read to the end.
+type recordingBody struct {
+	io.ReadCloser
+	buf      bytes.Buffer
+	complete bool
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF {
+		b.complete = true
+	}
+	return n, err
+}
@@ -0,0 +1,281 @@
+package ollama
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/wiseinf/ollama-go/ollamatest"
+)
+
+// cacheModels are the models listed by the cache tests' server.
+var cacheModels = []ollamatest.Model{
+	{Name: "llama3.2:1b", Digest: "sha256:aaa"},
+	{Name: "nomic-embed-text:latest", Digest: "sha256:bbb"},
+}
+
+func TestCacheDeterministicGenerate(t *testing.T) {
+	cache := NewCache(NewLRUCache(100))
+	server, client := newTestServer(t, withModels(cacheModels...), withClientOptions(WithMiddleware(cache.Middleware())))
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		options  map[string]interface{}
+		wantHits int
+	}{
+		{"temperature 0", ctx, map[string]interface{}{"temperature": 0}, 1},
+		{"seed", ctx, map[string]interface{}{"seed": 42, "temperature": 0.8}, 1},
+		{"sampled", ctx, map[string]interface{}{"temperature": 0.8}, 2},
+		{"no options", ctx, nil, 2},
+		{"forced", ContextWithForceCache(ctx), nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := server.Hits("/api/generate")
+			var responses []string
+			for i := 0; i < 2; i++ {
+				resp, err := client.Generate(tt.ctx, &GenerateRequest{Model: "llama3.2:1b", Prompt: tt.name, Options: tt.options})
+				if err != nil {
+					t.Fatalf("Generate() error = %v", err)
+				}
+				responses = append(responses, resp.Response)
+			}
+			if hits := server.Hits("/api/generate") - before; hits != tt.wantHits {
+				t.Errorf("server hits = %d, want %d", hits, tt.wantHits)
+			}
+			if responses[0] != responses[1] {
+				t.Errorf("responses = %q, want equal", responses)
+			}
+		})
+	}
+	if got := cache.Stats(); got != (CacheStats{Hits: 3, Misses: 3}) {
+		t.Errorf("Stats() = %+v, want 3 hits and 3 misses", got)
+	}
+}
+
+func TestCacheLoadUnload(t *testing.T) {
+	cache := NewCache(NewLRUCache(100), WithForceCache(true))
+	server, client := newTestServer(t, withModels(cacheModels...), withClientOptions(WithMiddleware(cache.Middleware())))
+	ctx := context.Background()
+
+	if err := client.LoadModel(ctx, "llama3.2:1b", 10*time.Minute); err != nil {
+		t.Fatalf("LoadModel() error = %v", err)
+	}
+	if err := client.UnloadModel(ctx, "llama3.2:1b"); err != nil {
+		t.Fatalf("UnloadModel() error = %v", err)
+	}
+	if hits := server.Hits("/api/generate"); hits != 2 {
+		t.Errorf("generate hits = %d, want load and unload both sent", hits)
+	}
+	if got := cache.Stats(); got != (CacheStats{}) {
+		t.Errorf("Stats() = %+v, want prompt-less generates bypassing the cache", got)
+	}
+}
+
+func TestCacheDigestListing(t *testing.T) {
+	metrics := NewExpvarMetrics("")
+	server, client := newTestServer(t, withModels(cacheModels...),
+		withClientOptions(WithMiddleware(NewCache(NewLRUCache(100)).Middleware()), WithMetrics(metrics)))
+	ctx := ContextWithForceCache(context.Background())
+
+	for _, model := range []string{"llama3.2:1b", "missing", "missing", "llama3.2:1b"} {
+		if _, err := client.Generate(ctx, &GenerateRequest{Model: model, Prompt: "Hi"}); err != nil {
+			t.Fatalf("Generate(%s) error = %v", model, err)
+		}
+	}
+	if hits := server.Hits("/api/tags"); hits != 1 {
+		t.Errorf("tags hits = %d, want one listing while it is fresh", hits)
+	}
+	if got := metrics.Counter("ollama_requests_total", "/api/tags", ""); got != 1 {
+		t.Errorf("observed tags requests = %v, want 1", got)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	server, client := newTestServer(t, withModels(cacheModels...), withClientOptions(WithMiddleware(NewCache(NewLRUCache(100), WithDigestTTL(0)).Middleware())))
+	ctx := context.Background()
+	chat := func(keepAlive Duration, content string) {
+		t.Helper()
+		req := &ChatRequest{
+			Model:     "llama3.2:1b",
+			Messages:  []ChatMessage{{Role: UserRole, Content: content}},
+			Options:   map[string]interface{}{"seed": 1},
+			KeepAlive: keepAlive,
+		}
+		if _, err := client.Chat(ctx, req); err != nil {
+			t.Fatalf("Chat() error = %v", err)
+		}
+	}
+
+	chat(Duration(time.Minute), "Hi")
+	chat(Duration(time.Hour), "Hi")
+	if hits := server.Hits("/api/chat"); hits != 1 {
+		t.Errorf("hits = %d, want keep_alive ignored by the key", hits)
+	}
+	chat(0, "Hello")
+	if hits := server.Hits("/api/chat"); hits != 2 {
+		t.Errorf("hits = %d, want a different message to miss", hits)
+	}
+
+	server.Models[0].Digest = "sha256:ccc"
+	chat(0, "Hi")
+	if hits := server.Hits("/api/chat"); hits != 3 {
+		t.Errorf("hits = %d, want a new model digest to miss", hits)
+	}
+}
+
+func TestCacheHitUsage(t *testing.T) {
+	metrics := NewExpvarMetrics("")
+	server, client := newTestServer(t, withModels(cacheModels...),
+		withClientOptions(WithMiddleware(NewCache(NewLRUCache(100)).Middleware()), WithMetrics(metrics)))
+	ctx := ContextWithForceCache(context.Background())
+	generate := func() {
+		t.Helper()
+		if _, err := client.Generate(ctx, &GenerateRequest{Model: "llama3.2:1b", Prompt: "Hi"}); err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		stream, err := client.GenerateStream(ctx, &GenerateRequest{Model: "llama3.2:1b", Prompt: "Hi"})
+		if err != nil {
+			t.Fatalf("GenerateStream() error = %v", err)
+		}
+		for range stream {
+		}
+	}
+
+	generate()
+	usage := client.Usage().Total()
+	tokens := metrics.Counter("ollama_eval_tokens_total", "llama3.2:1b")
+	if usage.Requests != 2 || tokens == 0 {
+		t.Fatalf("usage = %+v, eval tokens = %v after two misses", usage, tokens)
+	}
+
+	generate()
+	if hits := server.Hits("/api/generate"); hits != 2 {
+		t.Fatalf("generate hits = %d, want the second calls cached", hits)
+	}
+	if got := client.Usage().Total(); got != usage {
+		t.Errorf("usage = %+v after cache hits, want %+v", got, usage)
+	}
+	if got := metrics.Counter("ollama_eval_tokens_total", "llama3.2:1b"); got != tokens {
+		t.Errorf("eval tokens = %v after cache hits, want %v", got, tokens)
+	}
+}
+
+func TestCacheStreamReplay(t *testing.T) {
+	server, client := newTestServer(t, withModels(cacheModels...), withClientOptions(WithMiddleware(NewCache(NewLRUCache(100)).Middleware())))
+	read := func() []string {
+		t.Helper()
+		stream, err := client.ChatStream(context.Background(), &ChatRequest{
+			Model:    "llama3.2:1b",
+			Messages: []ChatMessage{{Role: UserRole, Content: "Hi"}},
+			Options:  map[string]interface{}{"temperature": 0},
+		})
+		if err != nil {
+			t.Fatalf("ChatStream() error = %v", err)
+		}
+		var chunks []string
+		for r := range stream {
+			if r.Error != nil {
+				t.Fatalf("stream error = %v", r.Error)
+			}
+			chunks = append(chunks, r.ChatResponse.Message.Content)
+		}
+		return chunks
+	}
+
+	first, second := read(), read()
+	if len(first) < 2 || !reflect.DeepEqual(first, second) {
+		t.Errorf("replayed chunks = %q, want %q", second, first)
+	}
+	if hits := server.Hits("/api/chat"); hits != 1 {
+		t.Errorf("hits = %d, want the second stream replayed", hits)
+	}
+
+	// Streams cut short are not cached.
+	server.Inject("/api/chat", ollamatest.Fault{ErrorAt: 2})
+	for i := 0; i < 2; i++ {
+		stream, err := client.ChatStream(context.Background(), &ChatRequest{Model: "llama3.2:1b", Messages: []ChatMessage{{Role: UserRole, Content: "Bye"}}, Options: map[string]interface{}{"seed": 1}})
+		if err != nil {
+			t.Fatalf("ChatStream() error = %v", err)
+		}
+		for range stream {
+		}
+	}
+	if hits := server.Hits("/api/chat"); hits != 3 {
+		t.Errorf("hits = %d, want a failed stream not to be cached", hits)
+	}
+}
+
+func TestCacheEmbeddings(t *testing.T) {
+	server, client := newTestServer(t, withModels(cacheModels...), withClientOptions(WithMiddleware(NewCache(NewLRUCache(100)).Middleware())))
+	for i := 0; i < 3; i++ {
+		resp, err := client.Embeddings(context.Background(), &EmbeddingRequest{Model: "nomic-embed-text", Prompt: "Hi"})
+		if err != nil {
+			t.Fatalf("Embeddings() error = %v", err)
+		}
+		if len(resp.Embedding) != len(server.Embedding) {
+			t.Errorf("embedding = %v, want %v", resp.Embedding, server.Embedding)
+		}
+	}
+	if hits := server.Hits("/api/embeddings"); hits != 1 {
+		t.Errorf("hits = %d, want 1", hits)
+	}
+	if hits := server.Hits("/api/tags"); hits != 1 {
+		t.Errorf("tags hits = %d, want digests reused", hits)
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := NewLRUCache(2)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", []byte("1"), 0)
+	c.Set("b", []byte("2"), time.Minute)
+	c.Get("a")
+	c.Set("c", []byte("3"), 0)
+	if _, ok := c.Get("b"); ok {
+		t.Error("least recently used entry was not evicted")
+	}
+	if v, ok := c.Get("a"); !ok || string(v) != "1" {
+		t.Errorf("Get(a) = %q, %v", v, ok)
+	}
+
+	c.Set("d", []byte("4"), time.Minute)
+	now = now.Add(2 * time.Minute)
+	if _, ok := c.Get("d"); ok {
+		t.Error("expired entry returned")
+	}
+	if c.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", c.Len())
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+	dir := t.TempDir()
+	c, err := NewDiskCache(dir)
+	if err != nil {
+		t.Fatalf("NewDiskCache() error = %v", err)
+	}
+	if err := c.Set("key", []byte("line1\nline2\n"), 0); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := c.Set("short", []byte("x"), time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	reopened, _ := NewDiskCache(dir)
+	if v, ok := reopened.Get("key"); !ok || string(v) != "line1\nline2\n" {
+		t.Errorf("Get(key) = %q, %v, want the stored value", v, ok)
+	}
+	reopened.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, ok := reopened.Get("short"); ok {
+		t.Error("expired entry returned")
+	}
+	if _, ok := reopened.Get("missing"); ok {
+		t.Error("Get(missing) found an entry")
+	}
+}
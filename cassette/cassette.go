@@ -0,0 +1,372 @@
+// Package cassette records HTTP interactions with an Ollama server to a file
+// and replays them later, so client flows can be tested without a server.
+//
+// A Recorder is an http.RoundTripper and plugs into the client through
+// ollama.WithHTTPClient:
+//
+//	rec, err := cassette.New("testdata/chat.json", cassette.ModeReplayOrRecord)
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer rec.Save()
+//	client := ollama.NewClient(ollama.WithHTTPClient(rec.Client()))
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Mode controls whether a Recorder talks to the real server.
+type Mode int
+
+const (
+	// ModeReplay serves every request from the cassette and fails requests
+	// that have no recorded interaction.
+	ModeReplay Mode = iota
+	// ModeRecord sends every request to the real server and records it,
+	// replacing the cassette contents.
+	ModeRecord
+	// ModeReplayOrRecord replays recorded interactions and records the ones
+	// that are missing.
+	ModeReplayOrRecord
+)
+
+// ErrNoInteraction is returned in replay mode when no recorded interaction
+// matches a request.
+var ErrNoInteraction = errors.New("cassette: no recorded interaction matches request")
+
+// Cassette is the on-disk representation of recorded interactions.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a single recorded request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request identifies a recorded request.
+type Request struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Body is the normalized request body. JSON bodies are re-encoded with
+	// sorted keys so that field order does not affect matching.
+	Body string `json:"body,omitempty"`
+}
+
+// Response is a recorded response. Streaming bodies are kept as the chunks
+// the client read, so NDJSON streams replay line by line.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Chunks     []Chunk     `json:"chunks"`
+}
+
+// Chunk is a piece of a response body.
+type Chunk struct {
+	Data string `json:"data"`
+	// Delay is the time between this chunk and the previous one, or for
+	// the first chunk since the request was sent. It is only recorded when
+	// timing is enabled.
+	Delay time.Duration `json:"delay,omitempty"`
+}
+
+// Option configures a Recorder.
+type Option func(*Recorder)
+
+// WithTransport sets the transport used to reach the real server.
+// Defaults to http.DefaultTransport.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(r *Recorder) {
+		r.transport = rt
+	}
+}
+
+// WithTiming records the delay between body chunks and reproduces it on
+// replay, which is useful when testing time-to-first-token logic.
+func WithTiming() Option {
+	return func(r *Recorder) {
+		r.timing = true
+	}
+}
+
+// Recorder is an http.RoundTripper that records and replays interactions.
+type Recorder struct {
+	path      string
+	mode      Mode
+	transport http.RoundTripper
+	timing    bool
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// New creates a Recorder backed by the cassette file at path. In replay
+// modes the file is loaded if it exists; ModeReplay requires it to exist.
+func New(path string, mode Mode, opts ...Option) (*Recorder, error) {
+	r := &Recorder{
+		path:      path,
+		mode:      mode,
+		transport: http.DefaultTransport,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if mode != ModeRecord {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(data, &r.cassette); err != nil {
+				return nil, fmt.Errorf("cassette: failed to decode %s: %w", path, err)
+			}
+		case errors.Is(err, os.ErrNotExist) && mode == ModeReplayOrRecord:
+		default:
+			return nil, fmt.Errorf("cassette: failed to load %s: %w", path, err)
+		}
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+
+	return r, nil
+}
+
+// Client returns an http.Client that uses the Recorder as its transport.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Interactions returns a copy of the interactions currently held.
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.cassette.Interactions...)
+}
+
+// Save writes the cassette to disk. It is a no-op in ModeReplay.
+func (r *Recorder) Save() error {
+	if r.mode == ModeReplay {
+		return nil
+	}
+
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("cassette: failed to encode: %w", err)
+	}
+
+	if dir := filepath.Dir(r.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("cassette: %w", err)
+		}
+	}
+	return os.WriteFile(r.path, append(data, '\n'), 0o644)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	key, body, err := requestKey(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.mode != ModeRecord {
+		if in, ok := r.match(key); ok {
+			return r.replay(req, in), nil
+		}
+		if r.mode == ModeReplay {
+			return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, key.Method, key.Path)
+		}
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	// The first chunk's delay includes the wait for the response headers,
+	// so that replays reproduce the time to first token
+	start := time.Now()
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = &recordingBody{
+		body:     resp.Body,
+		recorder: r,
+		start:    start,
+		interaction: Interaction{
+			Request: key,
+			Response: Response{
+				StatusCode: resp.StatusCode,
+				Header:     resp.Header.Clone(),
+			},
+		},
+	}
+	return resp, nil
+}
+
+// match returns the first unused interaction matching key. Once every match
+// has been used the last one is served again, so retried or repeated
+// requests keep working.
+func (r *Recorder) match(key Request) (Interaction, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	last := -1
+	for i, in := range r.cassette.Interactions {
+		if in.Request != key {
+			continue
+		}
+		if !r.used[i] {
+			r.used[i] = true
+			return in, true
+		}
+		last = i
+	}
+	if last >= 0 {
+		return r.cassette.Interactions[last], true
+	}
+	return Interaction{}, false
+}
+
+func (r *Recorder) add(in Interaction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, in)
+	r.used = append(r.used, true)
+}
+
+func (r *Recorder) replay(req *http.Request, in Interaction) *http.Response {
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+		StatusCode: in.Response.StatusCode,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     in.Response.Header.Clone(),
+		Body: &replayBody{
+			chunks: in.Response.Chunks,
+			timing: r.timing,
+			done:   req.Context().Done(),
+		},
+		ContentLength: -1,
+		Request:       req,
+	}
+}
+
+// requestKey builds the matching key for req and returns the raw body so it
+// can be sent on when recording.
+func requestKey(req *http.Request) (Request, []byte, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return Request{}, nil, fmt.Errorf("cassette: failed to read request body: %w", err)
+		}
+	}
+
+	return Request{
+		Method: req.Method,
+		Path:   req.URL.RequestURI(),
+		Body:   normalize(body),
+	}, body, nil
+}
+
+// normalize re-encodes JSON bodies with sorted keys and no insignificant
+// whitespace. Other bodies are returned unchanged.
+func normalize(body []byte) string {
+	body = bytes.TrimSpace(body)
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return string(body)
+	}
+	normalized, err := json.Marshal(v)
+	if err != nil {
+		return string(body)
+	}
+	return string(normalized)
+}
+
+// recordingBody captures a response body as the client reads it and adds
+// the interaction to the cassette once the body is fully read or closed.
+type recordingBody struct {
+	body        io.ReadCloser
+	recorder    *Recorder
+	interaction Interaction
+	start       time.Time
+	once        sync.Once
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 {
+		chunk := Chunk{Data: string(p[:n])}
+		if b.recorder.timing {
+			now := time.Now()
+			chunk.Delay = now.Sub(b.start)
+			b.start = now
+		}
+		b.interaction.Response.Chunks = append(b.interaction.Response.Chunks, chunk)
+	}
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	b.finish()
+	return b.body.Close()
+}
+
+func (b *recordingBody) finish() {
+	b.once.Do(func() {
+		b.recorder.add(b.interaction)
+	})
+}
+
+// replayBody serves recorded chunks, optionally waiting out their delays.
+type replayBody struct {
+	chunks  []Chunk
+	pending []byte
+	timing  bool
+	done    <-chan struct{}
+}
+
+func (b *replayBody) Read(p []byte) (int, error) {
+	for len(b.pending) == 0 {
+		if len(b.chunks) == 0 {
+			return 0, io.EOF
+		}
+		chunk := b.chunks[0]
+		b.chunks = b.chunks[1:]
+		if b.timing && chunk.Delay > 0 {
+			select {
+			case <-time.After(chunk.Delay):
+			case <-b.done:
+				return 0, errors.New("cassette: request canceled")
+			}
+		}
+		b.pending = []byte(chunk.Data)
+	}
+
+	n := copy(p, b.pending)
+	b.pending = b.pending[n:]
+	return n, nil
+}
+
+func (b *replayBody) Close() error {
+	return nil
+}
@@ -0,0 +1,115 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	ollama "github.com/wiseinf/ollama-go"
+	"github.com/wiseinf/ollama-go/ollamatest"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "generate.json")
+	server := ollamatest.NewServer()
+
+	rec, err := New(path, ModeRecord)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	client := ollama.NewClient(ollama.WithBaseURL(server.URL), ollama.WithHTTPClient(rec.Client()))
+
+	wantResp, err := client.Generate(context.Background(), &ollama.GenerateRequest{Model: "llama3.2:1b", Prompt: "Hello"})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	wantChunks := collectStream(t, client)
+	if err := rec.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	server.Close()
+
+	replay, err := New(path, ModeReplay)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	client = ollama.NewClient(ollama.WithBaseURL(server.URL), ollama.WithHTTPClient(replay.Client()))
+
+	gotResp, err := client.Generate(context.Background(), &ollama.GenerateRequest{Model: "llama3.2:1b", Prompt: "Hello"})
+	if err != nil {
+		t.Fatalf("replayed Generate() error = %v", err)
+	}
+	if !reflect.DeepEqual(gotResp, wantResp) {
+		t.Errorf("replayed Generate() = %+v, want %+v", gotResp, wantResp)
+	}
+	if got := collectStream(t, client); !reflect.DeepEqual(got, wantChunks) {
+		t.Errorf("replayed GenerateStream() = %q, want %q", got, wantChunks)
+	}
+}
+
+func TestReplayMissingInteraction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.json")
+	rec, err := New(path, ModeReplayOrRecord)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	rec.mode = ModeReplay
+
+	client := ollama.NewClient(ollama.WithBaseURL("http://ollama.invalid"), ollama.WithHTTPClient(rec.Client()), ollama.WithMaxRetries(0))
+	_, err = client.Generate(context.Background(), &ollama.GenerateRequest{Model: "llama3.2:1b", Prompt: "Hello"})
+	if !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("Generate() error = %v, want ErrNoInteraction", err)
+	}
+}
+
+func TestReplayWithTiming(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "drip.json")
+	server := ollamatest.NewServer()
+	server.Reply = "a b c"
+	server.Inject("/api/generate", ollamatest.Fault{Drip: 30 * time.Millisecond})
+
+	rec, err := New(path, ModeReplayOrRecord, WithTiming())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	client := ollama.NewClient(ollama.WithBaseURL(server.URL), ollama.WithHTTPClient(rec.Client()))
+	collectStream(t, client)
+	server.Close()
+
+	start := time.Now()
+	collectStream(t, client)
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("timed replay took %v, want the recorded chunk delays", elapsed)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	a := normalize([]byte(`{"model": "llama2", "options": {"seed": 1, "temperature": 0}}`))
+	b := normalize([]byte("{\"options\":{\"temperature\":0,\"seed\":1},\"model\":\"llama2\"}\n"))
+	if a != b {
+		t.Errorf("normalize() = %s and %s, want equal", a, b)
+	}
+	if got := normalize([]byte("not json")); got != "not json" {
+		t.Errorf("normalize() = %q, want body unchanged", got)
+	}
+}
+
+func collectStream(t *testing.T, client *ollama.Client) []string {
+	t.Helper()
+	stream, err := client.GenerateStream(context.Background(), &ollama.GenerateRequest{Model: "llama3.2:1b", Prompt: "Stream"})
+	if err != nil {
+		t.Fatalf("GenerateStream() error = %v", err)
+	}
+	var chunks []string
+	for item := range stream {
+		if item.Error != nil {
+			t.Fatalf("GenerateStream() stream error = %v", item.Error)
+		}
+		chunks = append(chunks, item.GenerateResponse.Response)
+	}
+	return strings.Fields(strings.Join(chunks, " "))
+}
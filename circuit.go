@@ -0,0 +1,302 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is matched by errors.Is for calls rejected by an open
+// circuit breaker.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// CircuitOpenError is returned without contacting the server while the
+// circuit for a host, or host and model, is open.
+type CircuitOpenError struct {
+	Host  string
+	Model string
+	// RetryAfter is the time left until the circuit lets a probe through.
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	if e.Model != "" {
+		return fmt.Sprintf("circuit breaker open for %s model %s (retry in %s)", e.Host, e.Model, e.RetryAfter.Round(time.Millisecond))
+	}
+	return fmt.Sprintf("circuit breaker open for %s (retry in %s)", e.Host, e.RetryAfter.Round(time.Millisecond))
+}
+
+// Is reports whether target is ErrCircuitOpen.
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// CircuitState is the state of a circuit.
+type CircuitState int
+
+const (
+	// CircuitClosed lets calls through and counts failures.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects calls until the cool-down has passed.
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of probe calls through. A
+	// success closes the circuit and a failure opens it again.
+	CircuitHalfOpen
+)
+
+// String returns the name of the state.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half_open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+// CircuitMetrics is implemented by Metrics that record circuit breaker
+// state changes.
+type CircuitMetrics interface {
+	ObserveCircuitState(host, model string, from, to CircuitState)
+}
+
+// CircuitBreakerOptions configures a CircuitBreaker
+type CircuitBreakerOptions struct {
+	// FailureThreshold is the number of consecutive failures that opens
+	// a circuit.
+	FailureThreshold int
+	// CoolDown is how long a circuit stays open before letting probes
+	// through.
+	CoolDown time.Duration
+	// HalfOpenRequests is the number of probes let through while half-open.
+	HalfOpenRequests int
+	// PerModel keeps a separate circuit for each model on a host.
+	PerModel bool
+}
+
+// CircuitBreakerOption is a function that modifies the circuit breaker options
+type CircuitBreakerOption func(*CircuitBreakerOptions)
+
+// WithFailureThreshold sets the consecutive failures that open a circuit
+func WithFailureThreshold(n int) CircuitBreakerOption {
+	return func(o *CircuitBreakerOptions) {
+		o.FailureThreshold = n
+	}
+}
+
+// WithCoolDown sets how long a circuit stays open
+func WithCoolDown(d time.Duration) CircuitBreakerOption {
+	return func(o *CircuitBreakerOptions) {
+		o.CoolDown = d
+	}
+}
+
+// WithHalfOpenRequests sets the number of probes allowed while half-open
+func WithHalfOpenRequests(n int) CircuitBreakerOption {
+	return func(o *CircuitBreakerOptions) {
+		o.HalfOpenRequests = n
+	}
+}
+
+// WithPerModel keeps a circuit per host and model instead of per host
+func WithPerModel(perModel bool) CircuitBreakerOption {
+	return func(o *CircuitBreakerOptions) {
+		o.PerModel = perModel
+	}
+}
+
+// CircuitBreaker fails calls fast once a host keeps failing. Connection
+// errors and 5xx responses count as failures; every other response counts
+// as a success. A breaker may be shared by several clients, e.g. the
+// clients of a Pool, and keeps one circuit per host.
+type CircuitBreaker struct {
+	opts *CircuitBreakerOptions
+	now  func() time.Time
+
+	mu       sync.Mutex
+	circuits map[circuitKey]*circuit
+}
+
+type circuitKey struct {
+	host  string
+	model string
+}
+
+type circuit struct {
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probes   int
+}
+
+// NewCircuitBreaker creates a circuit breaker. By default a circuit opens
+// after 5 consecutive failures and lets one probe through after 30 seconds.
+func NewCircuitBreaker(options ...CircuitBreakerOption) *CircuitBreaker {
+	opts := &CircuitBreakerOptions{
+		FailureThreshold: 5,
+		CoolDown:         30 * time.Second,
+		HalfOpenRequests: 1,
+	}
+	for _, opt := range options {
+		opt(opts)
+	}
+	return &CircuitBreaker{opts: opts, now: time.Now, circuits: make(map[circuitKey]*circuit)}
+}
+
+// WithCircuitBreaker guards requests with b
+func WithCircuitBreaker(b *CircuitBreaker) func(*ClientOptions) {
+	return func(o *ClientOptions) {
+		o.CircuitBreaker = b
+	}
+}
+
+// State returns the state of the circuit for host and, with PerModel,
+// model. host is the client's base URL.
+func (b *CircuitBreaker) State(host, model string) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c, ok := b.circuits[b.key(host, model)]; ok {
+		return c.state
+	}
+	return CircuitClosed
+}
+
+func (b *CircuitBreaker) key(host, model string) circuitKey {
+	if !b.opts.PerModel {
+		model = ""
+	}
+	return circuitKey{host: host, model: model}
+}
+
+func (b *CircuitBreaker) circuit(key circuitKey) *circuit {
+	c, ok := b.circuits[key]
+	if !ok {
+		c = &circuit{}
+		b.circuits[key] = c
+	}
+	return c
+}
+
+// allow reports whether a request may be sent. It returns the state the
+// circuit was in before the call.
+func (b *CircuitBreaker) allow(key circuitKey) (CircuitState, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(key)
+	from := c.state
+	switch c.state {
+	case CircuitOpen:
+		if wait := b.opts.CoolDown - b.now().Sub(c.openedAt); wait > 0 {
+			return from, &CircuitOpenError{Host: key.host, Model: key.model, RetryAfter: wait}
+		}
+		c.state = CircuitHalfOpen
+		c.probes = 1
+	case CircuitHalfOpen:
+		if c.probes >= b.opts.HalfOpenRequests {
+			return from, &CircuitOpenError{Host: key.host, Model: key.model}
+		}
+		c.probes++
+	}
+	return from, nil
+}
+
+// release gives back a half-open probe slot taken by allow for a request
+// whose outcome is unknown, e.g. because it was cancelled or never sent.
+func (b *CircuitBreaker) release(key circuitKey) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c := b.circuit(key); c.state == CircuitHalfOpen && c.probes > 0 {
+		c.probes--
+	}
+}
+
+// record records the outcome of a request and returns the states before
+// and after it.
+func (b *CircuitBreaker) record(key circuitKey, ok bool) (from, to CircuitState) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(key)
+	from = c.state
+	switch {
+	case ok && c.state != CircuitOpen:
+		c.state = CircuitClosed
+		c.failures = 0
+	case !ok && c.state == CircuitHalfOpen:
+		c.state = CircuitOpen
+		c.openedAt = b.now()
+	case !ok && c.state == CircuitClosed:
+		c.failures++
+		if c.failures >= b.opts.FailureThreshold {
+			c.state = CircuitOpen
+			c.openedAt = b.now()
+		}
+	}
+	return from, c.state
+}
+
+// allowCircuit checks the client's circuit breaker, if any, before an
+// attempt.
+func (c *Client) allowCircuit(ctx context.Context, call *Call) error {
+	if c.breaker == nil {
+		return nil
+	}
+	key := c.breaker.key(c.baseURL, call.Model())
+	from, err := c.breaker.allow(key)
+	if err != nil {
+		return err
+	}
+	if from == CircuitOpen {
+		c.circuitChanged(ctx, key, from, CircuitHalfOpen)
+	}
+	return nil
+}
+
+// releaseCircuit gives back the attempt's half-open probe slot, if any,
+// when the attempt ends without an outcome.
+func (c *Client) releaseCircuit(call *Call) {
+	if c.breaker != nil {
+		c.breaker.release(c.breaker.key(c.baseURL, call.Model()))
+	}
+}
+
+// recordCircuit records the outcome of an attempt with the client's
+// circuit breaker, if any. A cancelled attempt says nothing about the host,
+// so it only releases its probe slot.
+func (c *Client) recordCircuit(ctx context.Context, call *Call, resp *http.Response, err error) {
+	if c.breaker == nil {
+		return
+	}
+	if ctx.Err() != nil {
+		c.releaseCircuit(call)
+		return
+	}
+	ok := err == nil && resp.StatusCode < 500
+	key := c.breaker.key(c.baseURL, call.Model())
+	if from, to := c.breaker.record(key, ok); from != to {
+		c.circuitChanged(ctx, key, from, to)
+	}
+}
+
+// circuitChanged reports a circuit state change to the logger and metrics.
+func (c *Client) circuitChanged(ctx context.Context, key circuitKey, from, to CircuitState) {
+	level := slog.LevelInfo
+	if to == CircuitOpen {
+		level = slog.LevelError
+	}
+	c.logger.LogAttrs(ctx, level, "circuit breaker state changed",
+		slog.String("host", key.host),
+		slog.String("model", key.model),
+		slog.String("from", from.String()),
+		slog.String("to", to.String()),
+	)
+	if m, ok := c.metrics.(CircuitMetrics); ok {
+		m.ObserveCircuitState(key.host, key.model, from, to)
+	}
+}
@@ -0,0 +1,198 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wiseinf/ollama-go/ollamatest"
+)
+
+// fakeClock is a settable time source for circuit breaker tests.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestBreaker(opts ...CircuitBreakerOption) (*CircuitBreaker, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	b := NewCircuitBreaker(opts...)
+	b.now = clock.Now
+	return b, clock
+}
+
+func listModels(client *Client) error {
+	_, err := client.ListModels(context.Background())
+	return err
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	breaker, clock := newTestBreaker(WithFailureThreshold(2), WithCoolDown(time.Minute))
+	metrics := NewExpvarMetrics("")
+	logger := &recordingLogger{}
+	server, client := newTestServer(t, withClientOptions(WithMaxRetries(0), WithCircuitBreaker(breaker), WithMetrics(metrics), WithLogger(logger)))
+
+	server.Inject("/api/tags", ollamatest.Fault{Times: 3, Status: http.StatusInternalServerError})
+	for i := 0; i < 2; i++ {
+		if err := listModels(client); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("call %d error = %v, want a server error", i, err)
+		}
+	}
+	if got := breaker.State(client.baseURL, ""); got != CircuitOpen {
+		t.Fatalf("State() = %v, want open", got)
+	}
+
+	err := listModels(client)
+	var openErr *CircuitOpenError
+	if !errors.As(err, &openErr) || !errors.Is(err, ErrCircuitOpen) || openErr.RetryAfter != time.Minute {
+		t.Fatalf("error while open = %v, want CircuitOpenError with a minute left", err)
+	}
+	if hits := server.Hits("/api/tags"); hits != 2 {
+		t.Errorf("hits = %d, want no request while open", hits)
+	}
+
+	// A failed probe opens the circuit again.
+	clock.Advance(time.Minute)
+	if err := listModels(client); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("probe error = %v, want a server error", err)
+	}
+	if got := breaker.State(client.baseURL, ""); got != CircuitOpen {
+		t.Fatalf("State() after failed probe = %v, want open", got)
+	}
+
+	// A successful probe closes it.
+	clock.Advance(time.Minute)
+	if err := listModels(client); err != nil {
+		t.Fatalf("probe error = %v", err)
+	}
+	if got := breaker.State(client.baseURL, ""); got != CircuitClosed {
+		t.Errorf("State() after successful probe = %v, want closed", got)
+	}
+
+	for state, want := range map[string]float64{"open": 2, "half_open": 2, "closed": 1} {
+		if got := metrics.Counter("ollama_circuit_state_changes_total", client.baseURL, "", state); got != want {
+			t.Errorf("state changes to %s = %v, want %v", state, got, want)
+		}
+	}
+	if logs := strings.Join(logger.lines, "\n"); !strings.Contains(logs, "ERROR circuit breaker state changed host="+client.baseURL+` model="" from=closed to=open`) {
+		t.Errorf("logs missing state change:\n%s", logs)
+	}
+}
+
+func TestCircuitBreakerStopsRetries(t *testing.T) {
+	breaker, _ := newTestBreaker(WithFailureThreshold(2))
+	server, client := newTestServer(t, withClientOptions(WithMaxRetries(5), WithRetryWaitTime(time.Millisecond),
+		WithCircuitBreaker(breaker)))
+
+	server.Inject("/api/tags", ollamatest.Fault{Times: 10, Status: http.StatusServiceUnavailable})
+	if err := listModels(client); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("error = %v, want ErrCircuitOpen once the circuit opens", err)
+	}
+	if hits := server.Hits("/api/tags"); hits != 2 {
+		t.Errorf("hits = %d, want retries to stop at the threshold", hits)
+	}
+}
+
+func TestCircuitBreakerCancelledProbe(t *testing.T) {
+	breaker, clock := newTestBreaker(WithFailureThreshold(1), WithCoolDown(time.Minute))
+	server, client := newTestServer(t, withClientOptions(WithMaxRetries(0), WithCircuitBreaker(breaker)))
+
+	server.Inject("/api/tags", ollamatest.Fault{Status: http.StatusInternalServerError})
+	listModels(client)
+	clock.Advance(time.Minute)
+
+	// The probe is cancelled before the server answers, which says nothing
+	// about the host, so the next call may probe again.
+	server.Inject("/api/tags", ollamatest.Fault{Latency: time.Second})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.ListModels(ctx); err == nil {
+		t.Fatal("cancelled probe succeeded")
+	}
+	if got := breaker.State(client.baseURL, ""); got != CircuitHalfOpen {
+		t.Fatalf("State() after cancelled probe = %v, want half_open", got)
+	}
+	if err := listModels(client); err != nil {
+		t.Fatalf("second probe error = %v, want the circuit to let it through", err)
+	}
+	if got := breaker.State(client.baseURL, ""); got != CircuitClosed {
+		t.Errorf("State() after successful probe = %v, want closed", got)
+	}
+}
+
+func TestCircuitBreakerIgnoresClientErrors(t *testing.T) {
+	breaker, _ := newTestBreaker(WithFailureThreshold(1))
+	server, client := newTestServer(t, withClientOptions(WithCircuitBreaker(breaker)))
+
+	server.Inject("/api/show", ollamatest.Fault{Times: 3, Status: http.StatusNotFound})
+	for i := 0; i < 3; i++ {
+		client.ShowModel(context.Background(), "missing", nil)
+	}
+	if got := breaker.State(client.baseURL, ""); got != CircuitClosed {
+		t.Errorf("State() = %v, want 4xx responses not to open the circuit", got)
+	}
+}
+
+func TestCircuitBreakerPerModel(t *testing.T) {
+	breaker, _ := newTestBreaker(WithFailureThreshold(1), WithPerModel(true))
+	_, client := newTestServer(t,
+		withHandler("/api/generate", func(w http.ResponseWriter, r *http.Request) {
+			var req GenerateRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.Model == "big" {
+				w.WriteHeader(http.StatusInternalServerError)
+				io.WriteString(w, `{"error":"out of memory"}`)
+				return
+			}
+			io.WriteString(w, `{"model":"small","response":"ok","done":true}`)
+		}),
+		withClientOptions(WithMaxRetries(0), WithCircuitBreaker(breaker)),
+	)
+
+	generate := func(model string) error {
+		_, err := client.Generate(context.Background(), &GenerateRequest{Model: model, Prompt: "Hi"})
+		return err
+	}
+	generate("big")
+	if err := generate("big"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("big model error = %v, want ErrCircuitOpen", err)
+	}
+	if err := generate("small"); err != nil {
+		t.Errorf("small model error = %v, want its circuit unaffected", err)
+	}
+}
+
+func TestPoolFailsOverOnOpenCircuit(t *testing.T) {
+	breaker, _ := newTestBreaker(WithFailureThreshold(1))
+	pool, servers := newTestPool(t, 2, WithClientOptions(WithCircuitBreaker(breaker)))
+	servers[0].Inject("/api/generate", ollamatest.Fault{Times: 10, Status: http.StatusInternalServerError})
+
+	// The first call fails on the first server and opens its circuit; the
+	// rest go straight to the second server.
+	pool.Generate(context.Background(), &GenerateRequest{Model: "llama3.2:1b", Prompt: "Hi"})
+	generateN(t, pool, 4)
+	if hits := servers[0].Hits("/api/generate"); hits != 1 {
+		t.Errorf("failing server hits = %d, want 1", hits)
+	}
+	if hits := servers[1].Hits("/api/generate"); hits != 4 {
+		t.Errorf("healthy server hits = %d, want 4", hits)
+	}
+}
@@ -5,7 +5,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -14,8 +17,15 @@ type Client struct {
 	baseURL    string
 	httpClient *http.Client
 	opts       *ClientOptions
-	logger     Logger
+	logger     *slog.Logger
 	limiter    RateLimiter
+	usage      *UsageTracker
+	metrics    Metrics
+	tracer     Tracer
+	breaker    *CircuitBreaker
+	handler    Handler
+	// err is a configuration error returned by every call.
+	err error
 }
 
 // ClientOption is a function that modifies the client
@@ -27,69 +37,213 @@ func NewClient(options ...ClientOption) *Client {
 	for _, opt := range options {
 		opt(opts)
 	}
-	httpClient := opts.HTTPClient
-	if httpClient == nil {
-		httpClient = &http.Client{
-			Timeout: opts.Timeout,
-		}
+	httpClient, baseURL, err := newHTTPClient(opts)
+	usage := opts.UsageTracker
+	if usage == nil {
+		usage = NewUsageTracker()
+	}
+	metrics := opts.Metrics
+	if metrics == nil {
+		metrics = noopMetrics{}
+	}
+	tracer := opts.Tracer
+	if tracer == nil {
+		tracer = noopTracer{}
 	}
-	return &Client{
-		baseURL:    opts.BaseURL,
+	c := &Client{
+		baseURL:    baseURL,
 		opts:       opts,
 		httpClient: httpClient,
-		logger:     opts.Logger,
+		logger:     newSlogLogger(opts),
 		limiter:    newRateLimiter(opts.RateLimit),
+		usage:      usage,
+		metrics:    metrics,
+		tracer:     tracer,
+		breaker:    opts.CircuitBreaker,
+		err:        err,
 	}
+	c.handler = c.buildHandler()
+	return c
 }
 
-// sendRequest is a helper function to send requests with retries and rate limiting
-func (c *Client) sendRequest(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+// sendRequest is the innermost Handler. It sends the call with retries and rate limiting
+func (c *Client) sendRequest(ctx context.Context, call *Call) (*http.Response, error) {
+	method, path, body := call.Method, call.Endpoint, call.Request
+	if c.err != nil {
+		return nil, c.err
+	}
+
 	// Apply rate limiting
+	waitStart := time.Now()
 	if err := c.limiter.Wait(); err != nil {
 		return nil, fmt.Errorf("rate limit error: %w", err)
 	}
+	c.metrics.ObserveRateLimitWait(time.Since(waitStart))
+
+	var token string
+	if c.opts.TokenSource != nil {
+		var err error
+		if token, err = c.opts.TokenSource(ctx, false); err != nil {
+			return nil, fmt.Errorf("failed to get token: %w", err)
+		}
+	}
 
 	var resp *http.Response
 	var err error
+	var retryAfter time.Duration
+	// refreshed is set once the token has been refreshed after a 401, and
+	// reauth skips the backoff before retrying with the new token
+	var refreshed, reauth bool
 
 	// Retry logic
 	for attempt := 0; attempt <= c.opts.MaxRetries; attempt++ {
-		if attempt > 0 {
-			c.logger.Debug("Retrying request (attempt %d/%d)", attempt, c.opts.MaxRetries)
-			// Calculate backoff time
+		if err := c.allowCircuit(ctx, call); err != nil {
+			return nil, err
+		}
+		call.Attempts++
+		if attempt > 0 && !reauth {
+			c.metrics.ObserveRetry(path, call.Model())
+			// Calculate backoff time, preferring the server's Retry-After hint
 			waitTime := c.opts.RetryWaitTime * time.Duration(1<<uint(attempt-1))
+			if retryAfter > 0 {
+				waitTime = retryAfter
+			}
 			if waitTime > c.opts.RetryMaxWaitTime {
 				waitTime = c.opts.RetryMaxWaitTime
 			}
-			time.Sleep(waitTime)
+			c.logger.LogAttrs(ctx, slog.LevelDebug, "retrying request",
+				slog.String("endpoint", path),
+				slog.String("model", call.Model()),
+				slog.Int("attempt", call.Attempts),
+				slog.Int("max_retries", c.opts.MaxRetries),
+				slog.Duration("wait", waitTime),
+			)
+			select {
+			case <-time.After(waitTime):
+			case <-ctx.Done():
+				c.releaseCircuit(call)
+				return nil, fmt.Errorf("all retries failed: %w", ctx.Err())
+			}
 		}
+		// A Retry-After hint only applies to the attempt after the response
+		// that sent it
+		reauth, retryAfter = false, 0
 
 		var buf bytes.Buffer
-		if body != nil {
+		var reqBody io.Reader = &buf
+		contentType := "application/json"
+		upload, isUpload := body.(*uploadBody)
+		if isUpload {
+			// Uploads are sent as-is and reopened for every attempt; the
+			// transport closes them once sent
+			var rc io.ReadCloser
+			if rc, err = upload.open(); err != nil {
+				c.releaseCircuit(call)
+				return nil, fmt.Errorf("failed to open request body: %w", err)
+			}
+			reqBody = rc
+			contentType = "application/octet-stream"
+		} else if body != nil {
 			if err := json.NewEncoder(&buf).Encode(body); err != nil {
+				c.releaseCircuit(call)
 				return nil, fmt.Errorf("failed to encode request body: %w", err)
 			}
 		}
+		attemptCtx, span := c.tracer.Start(ctx, "ollama attempt", Attr("ollama.attempt", call.Attempts))
 		var req *http.Request
-		req, err = http.NewRequestWithContext(ctx, method, c.baseURL+path, &buf)
+		req, err = http.NewRequestWithContext(attemptCtx, method, c.baseURL+path, reqBody)
 		if err != nil {
+			if closer, ok := reqBody.(io.Closer); ok {
+				closer.Close()
+			}
+			c.releaseCircuit(call)
+			span.RecordError(err)
+			span.End()
 			continue
 		}
+		if isUpload {
+			req.ContentLength = upload.size
+		}
 
-		req.Header.Set("Content-Type", "application/json")
+		for key, values := range c.opts.Headers {
+			req.Header[key] = append([]string(nil), values...)
+		}
+		for key, values := range call.Header {
+			req.Header[key] = append([]string(nil), values...)
+		}
+		req.Header.Set("Content-Type", contentType)
+		c.setAuth(req, token)
+		if tp := traceParent(attemptCtx, span); tp != "" {
+			req.Header.Set("traceparent", tp)
+		}
 
-		c.logger.Debug("Sending request: %s %s", method, path)
-		resp, err = c.httpClient.Do(req)
+		if c.logger.Enabled(ctx, slog.LevelDebug) {
+			attrs := []slog.Attr{
+				slog.String("method", method),
+				slog.String("endpoint", path),
+				slog.String("model", call.Model()),
+				slog.Int("attempt", call.Attempts),
+				redactHeaders(req.Header),
+			}
+			if isUpload {
+				attrs = append(attrs, slog.Int64("size", upload.size))
+			} else if body != nil {
+				attrs = append(attrs, slog.Any("body", logBody{body: body, redact: c.opts.RedactLogs}))
+			}
+			c.logger.LogAttrs(ctx, slog.LevelDebug, "sending request", attrs...)
+		}
+		httpClient := c.httpClient
+		if isUpload && httpClient.Timeout > 0 {
+			// Uploads can take far longer than any other call, so only ctx
+			// bounds them
+			uploadClient := *httpClient
+			uploadClient.Timeout = 0
+			httpClient = &uploadClient
+		}
+		sent := time.Now()
+		resp, err = httpClient.Do(req)
+		c.recordCircuit(ctx, call, resp, err)
 		if err != nil {
-			c.logger.Error("Request failed: %v", err)
+			c.logger.LogAttrs(ctx, slog.LevelError, "request failed",
+				slog.String("endpoint", path),
+				slog.String("model", call.Model()),
+				slog.Int("attempt", call.Attempts),
+				slog.Duration("duration", time.Since(sent)),
+				slog.Any("error", err),
+			)
+			span.RecordError(err)
+			span.End()
 			continue
 		}
-		c.logger.Debug("Receiving response: %s %s", method, path)
-		if resp.StatusCode == http.StatusTooManyRequests ||
-			(resp.StatusCode >= 500 && resp.StatusCode < 600) {
+		c.logger.LogAttrs(ctx, slog.LevelDebug, "received response",
+			slog.String("endpoint", path),
+			slog.String("model", call.Model()),
+			slog.Int("attempt", call.Attempts),
+			slog.Int("status", resp.StatusCode),
+			slog.Duration("duration", time.Since(sent)),
+		)
+		call.StatusCode = resp.StatusCode
+		span.SetAttributes(Attr("http.status_code", resp.StatusCode))
+		span.End()
+		if resp.StatusCode == http.StatusUnauthorized && c.opts.TokenSource != nil && !refreshed {
 			resp.Body.Close()
+			refreshed = true
+			if token, err = c.opts.TokenSource(ctx, true); err != nil {
+				return nil, fmt.Errorf("failed to refresh token: %w", err)
+			}
+			// The retry with the new token does not count against MaxRetries
+			reauth = true
+			attempt--
 			continue
 		}
+		if resp.StatusCode == http.StatusTooManyRequests ||
+			(resp.StatusCode >= 500 && resp.StatusCode < 600) {
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+			if attempt < c.opts.MaxRetries {
+				resp.Body.Close()
+				continue
+			}
+		}
 
 		break
 	}
@@ -98,16 +252,30 @@ func (c *Client) sendRequest(ctx context.Context, method, path string, body inte
 		return nil, fmt.Errorf("all retries failed: %w", err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
 		defer resp.Body.Close()
 		var errResp struct {
 			Error string `json:"error"`
 		}
-		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
-			return nil, fmt.Errorf("http status %d", resp.StatusCode)
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || errResp.Error == "" {
+			return nil, &APIError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
 		}
-		return nil, fmt.Errorf("api error: %s", errResp.Error)
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: errResp.Error}
 	}
 
 	return resp, nil
 }
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
@@ -0,0 +1,303 @@
+package ollama
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wiseinf/ollama-go/ollamatest"
+)
+
+// testServerOptions configures newTestServer.
+type testServerOptions struct {
+	models   []ollamatest.Model
+	handlers map[string]http.HandlerFunc
+	client   []ClientOption
+}
+
+// testServerOption is a function that modifies testServerOptions.
+type testServerOption func(*testServerOptions)
+
+// withModels sets the models the server lists.
+func withModels(models ...ollamatest.Model) testServerOption {
+	return func(o *testServerOptions) {
+		o.models = append(o.models, models...)
+	}
+}
+
+// withHandler overrides the server's handler for path.
+func withHandler(path string, h http.HandlerFunc) testServerOption {
+	return func(o *testServerOptions) {
+		o.handlers[path] = h
+	}
+}
+
+// withClientOptions adds options to the client, after the defaults.
+func withClientOptions(opts ...ClientOption) testServerOption {
+	return func(o *testServerOptions) {
+		o.client = append(o.client, opts...)
+	}
+}
+
+// newTestServer starts a fake server that is closed when the test ends,
+// and a client for it with fast retries that logs to a recordingLogger.
+func newTestServer(t *testing.T, opts ...testServerOption) (*ollamatest.Server, *Client) {
+	t.Helper()
+	o := &testServerOptions{handlers: make(map[string]http.HandlerFunc)}
+	for _, opt := range opts {
+		opt(o)
+	}
+	server := ollamatest.NewServer()
+	t.Cleanup(server.Close)
+	server.Models = o.models
+	for path, h := range o.handlers {
+		server.Handle(path, h)
+	}
+	clientOpts := append([]ClientOption{
+		WithBaseURL(server.URL),
+		WithRetryWaitTime(10 * time.Millisecond),
+		WithLogger(&recordingLogger{}),
+	}, o.client...)
+	return server, NewClient(clientOpts...)
+}
+
+func TestSendRequestRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		fault    ollamatest.Fault
+		wantHits int
+		wantErr  bool
+	}{
+		{
+			name:     "5xx burst recovers",
+			fault:    ollamatest.Fault{Status: http.StatusServiceUnavailable, Times: 2},
+			wantHits: 3,
+		},
+		{
+			name:     "5xx burst exhausts retries",
+			fault:    ollamatest.Fault{Status: http.StatusInternalServerError, Times: 4, ErrorMessage: "out of memory"},
+			wantHits: 4,
+			wantErr:  true,
+		},
+		{
+			name:     "connection reset recovers",
+			fault:    ollamatest.Fault{Reset: true, Times: 2},
+			wantHits: 3,
+		},
+		{
+			name:     "client errors are not retried",
+			fault:    ollamatest.Fault{Status: http.StatusBadRequest},
+			wantHits: 1,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := newTestServer(t)
+			server.Inject("/api/generate", tt.fault)
+
+			_, err := client.Generate(context.Background(), &GenerateRequest{Model: "llama3.2:1b", Prompt: "Hello"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Generate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.fault.ErrorMessage != "" && (err == nil || !strings.Contains(err.Error(), tt.fault.ErrorMessage)) {
+				t.Errorf("Generate() error = %v, want message %q", err, tt.fault.ErrorMessage)
+			}
+			if got := server.Hits("/api/generate"); got != tt.wantHits {
+				t.Errorf("server hits = %d, want %d", got, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestSendRequestHonorsRetryAfter(t *testing.T) {
+	server, client := newTestServer(t)
+	server.Inject("/api/generate", ollamatest.Fault{Status: http.StatusTooManyRequests, RetryAfter: time.Second})
+
+	start := time.Now()
+	if _, err := client.Generate(context.Background(), &GenerateRequest{Model: "llama3.2:1b", Prompt: "Hello"}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Generate() returned after %v, want at least the Retry-After delay", elapsed)
+	}
+}
+
+func TestSendRequestRetryAfterNotReused(t *testing.T) {
+	server, client := newTestServer(t)
+	server.Inject("/api/generate", ollamatest.Fault{Status: http.StatusServiceUnavailable, RetryAfter: time.Second})
+	server.Inject("/api/generate", ollamatest.Fault{Reset: true, Times: 2})
+
+	start := time.Now()
+	if _, err := client.Generate(context.Background(), &GenerateRequest{Model: "llama3.2:1b", Prompt: "Hello"}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second || elapsed > 1500*time.Millisecond {
+		t.Errorf("Generate() returned after %v, want one Retry-After delay and then the normal backoff", elapsed)
+	}
+}
+
+func TestRetryAfterRoundedUp(t *testing.T) {
+	server, _ := newTestServer(t)
+	server.Inject("/api/tags", ollamatest.Fault{Status: http.StatusTooManyRequests, RetryAfter: 300 * time.Millisecond})
+
+	resp, err := http.Get(server.URL + "/api/tags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+}
+
+func TestStreamDripCancelled(t *testing.T) {
+	server, client := newTestServer(t)
+	server.Inject("/api/generate", ollamatest.Fault{Drip: time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if stream, err := client.GenerateStream(ctx, &GenerateRequest{Model: "llama3.2:1b", Prompt: "Hello"}); err == nil {
+		for range stream {
+		}
+	}
+	server.Close()
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("cancelled drip and Close took %v, want the drip cut short", elapsed)
+	}
+}
+
+func TestSendRequestLatencyTimeout(t *testing.T) {
+	server, client := newTestServer(t)
+	server.Inject("/api/generate", ollamatest.Fault{Latency: time.Second, Times: 10})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := client.Generate(ctx, &GenerateRequest{Model: "llama3.2:1b", Prompt: "Hello"}); err == nil {
+		t.Fatal("Generate() expected deadline error, got nil")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Generate() kept retrying for %v after the context expired", elapsed)
+	}
+}
+
+func TestStreamFaults(t *testing.T) {
+	tests := []struct {
+		name      string
+		fault     ollamatest.Fault
+		wantErr   string
+		wantCount int
+		// wantSpread is the least time between the first and last chunks,
+		// showing that chunks are delivered as they arrive.
+		wantSpread time.Duration
+	}{
+		{
+			name:      "truncated stream",
+			fault:     ollamatest.Fault{TruncateAfter: 2},
+			wantErr:   "unexpected EOF",
+			wantCount: 2,
+		},
+		{
+			name:      "malformed line",
+			fault:     ollamatest.Fault{MalformedAt: 2},
+			wantErr:   "invalid character",
+			wantCount: 1,
+		},
+		{
+			name:      "mid-stream error object",
+			fault:     ollamatest.Fault{ErrorAt: 3, ErrorMessage: "model runner has unexpectedly stopped"},
+			wantErr:   "model runner has unexpectedly stopped",
+			wantCount: 2,
+		},
+		{
+			name:       "slow drip",
+			fault:      ollamatest.Fault{Drip: 20 * time.Millisecond},
+			wantCount:  5,
+			wantSpread: 60 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := newTestServer(t)
+			server.Reply = "one two three four"
+
+			t.Run("generate", func(t *testing.T) {
+				server.Inject("/api/generate", tt.fault)
+				stream, err := client.GenerateStream(context.Background(), &GenerateRequest{Model: "llama3.2:1b", Prompt: "Hello"})
+				if err != nil {
+					t.Fatalf("GenerateStream() error = %v", err)
+				}
+				var count int
+				var streamErr error
+				var first, last time.Time
+				for item := range stream {
+					if item.Error != nil {
+						streamErr = item.Error
+						continue
+					}
+					if count == 0 {
+						first = time.Now()
+					}
+					last = time.Now()
+					count++
+				}
+				checkStreamResult(t, count, streamErr, tt.wantCount, tt.wantErr)
+				checkStreamSpread(t, last.Sub(first), tt.wantSpread)
+			})
+
+			t.Run("chat", func(t *testing.T) {
+				server.Inject("/api/chat", tt.fault)
+				stream, err := client.ChatStream(context.Background(), &ChatRequest{
+					Model:    "llama3.2:1b",
+					Messages: []ChatMessage{{Role: UserRole, Content: "Hello"}},
+				})
+				if err != nil {
+					t.Fatalf("ChatStream() error = %v", err)
+				}
+				var count int
+				var streamErr error
+				var first, last time.Time
+				for item := range stream {
+					if item.Error != nil {
+						streamErr = item.Error
+						continue
+					}
+					if count == 0 {
+						first = time.Now()
+					}
+					last = time.Now()
+					count++
+				}
+				checkStreamResult(t, count, streamErr, tt.wantCount, tt.wantErr)
+				checkStreamSpread(t, last.Sub(first), tt.wantSpread)
+			})
+		})
+	}
+}
+
+func checkStreamResult(t *testing.T, count int, err error, wantCount int, wantErr string) {
+	t.Helper()
+	if wantErr == "" && err != nil {
+		t.Errorf("stream error = %v, want nil", err)
+	}
+	if wantErr != "" && (err == nil || !strings.Contains(err.Error(), wantErr)) {
+		t.Errorf("stream error = %v, want %q", err, wantErr)
+	}
+	if count != wantCount {
+		t.Errorf("stream chunks = %d, want %d", count, wantCount)
+	}
+}
+
+func checkStreamSpread(t *testing.T, spread, wantSpread time.Duration) {
+	t.Helper()
+	if spread < wantSpread {
+		t.Errorf("stream chunks arrived within %v, want them spread over at least %v", spread, wantSpread)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wiseinf/ollama-go/batch"
+)
+
+func runBatch(ctx context.Context, e *env, args []string) error {
+	fs, g := newFlagSet(e, "batch", "INPUT OUTPUT")
+	concurrency := fs.Int("concurrency", 4, "number of requests to run at once")
+	resume := fs.Bool("resume", true, "skip jobs that already succeeded in OUTPUT and append to it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := g.validate(); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return usageError(fs, "batch requires an input and an output file")
+	}
+
+	stats, err := batch.RunFile(ctx, g.client(), fs.Arg(0), fs.Arg(1), *resume, batch.WithConcurrency(*concurrency))
+	if g.jsonOutput() {
+		if perr := printJSON(e.stdout, stats); perr != nil && err == nil {
+			err = perr
+		}
+	} else {
+		fmt.Fprintf(e.stdout, "total %d, skipped %d, succeeded %d, failed %d\n",
+			stats.Total, stats.Skipped, stats.Succeeded, stats.Failed)
+	}
+	return err
+}
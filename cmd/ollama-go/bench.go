@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/wiseinf/ollama-go/bench"
+)
+
+func runBench(ctx context.Context, e *env, args []string) error {
+	fs, g := newFlagSet(e, "bench", "MODEL...", optionsFlag)
+	var prompts []string
+	fs.Func("prompt", "prompt to send; may be repeated", func(s string) error {
+		prompts = append(prompts, s)
+		return nil
+	})
+	promptFile := fs.String("prompt-file", "", "read prompts from FILE, one per line")
+	levels := fs.String("concurrency", "1", "comma-separated concurrency levels")
+	requests := fs.Int("requests", 0, "requests per model and concurrency level (default: number of prompts)")
+	warmup := fs.Bool("warmup", true, "send an untimed request to load each model first")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := g.validate(); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		return usageError(fs, "bench requires at least one model")
+	}
+
+	if *promptFile != "" {
+		data, err := os.ReadFile(*promptFile)
+		if err != nil {
+			return err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				prompts = append(prompts, line)
+			}
+		}
+	}
+	if len(prompts) == 0 {
+		prompts = []string{"Why is the sky blue?"}
+	}
+
+	cfg := bench.Config{
+		Models:   fs.Args(),
+		Prompts:  prompts,
+		Requests: *requests,
+		Warmup:   *warmup,
+	}
+	for _, level := range strings.Split(*levels, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(level))
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid concurrency level %q", level)
+		}
+		cfg.Concurrency = append(cfg.Concurrency, n)
+	}
+	options, err := g.parseOptions()
+	if err != nil {
+		return err
+	}
+	cfg.Options = options
+
+	report, err := bench.Run(ctx, g.client(), cfg)
+	if report == nil {
+		return err
+	}
+	var werr error
+	if g.jsonOutput() {
+		werr = report.WriteJSON(e.stdout)
+	} else {
+		werr = report.WriteTable(e.stdout)
+	}
+	if err == nil {
+		err = werr
+	}
+	return err
+}
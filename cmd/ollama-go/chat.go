@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	ollama "github.com/wiseinf/ollama-go"
+)
+
+const chatHelp = `Commands:
+  /history       Show the conversation so far
+  /clear         Forget the conversation
+  /save FILE     Save the conversation as JSON
+  /load FILE     Load a conversation saved with /save
+  /bye           Exit
+`
+
+// chatSession holds the state of a chat REPL.
+type chatSession struct {
+	e       *env
+	g       *globalFlags
+	client  *ollama.Client
+	req     ollama.ChatRequest
+	history []ollama.ChatMessage
+}
+
+func runChat(ctx context.Context, e *env, args []string) error {
+	fs, g := newFlagSet(e, "chat", "MODEL [MESSAGE]", optionsFlag, systemFlag, keepAliveFlag)
+	historyFile := fs.String("history", "", "load the conversation from FILE and save it back on exit")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := g.validate(); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		return usageError(fs, "chat requires a model")
+	}
+
+	options, err := g.parseOptions()
+	if err != nil {
+		return err
+	}
+	keepAlive, err := g.parseKeepAlive()
+	if err != nil {
+		return err
+	}
+
+	s := &chatSession{
+		e:      e,
+		g:      g,
+		client: g.client(),
+		req: ollama.ChatRequest{
+			Model:     fs.Arg(0),
+			Options:   options,
+			KeepAlive: keepAlive,
+		},
+	}
+	if *historyFile != "" {
+		if err := s.load(*historyFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if len(s.history) == 0 && g.system != "" {
+		s.history = append(s.history, ollama.ChatMessage{Role: ollama.SystemRole, Content: g.system})
+	}
+
+	if message := strings.Join(fs.Args()[1:], " "); message != "" {
+		err = s.send(ctx, message)
+	} else {
+		err = s.repl(ctx)
+	}
+
+	if *historyFile != "" {
+		if saveErr := s.save(*historyFile); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}
+	return err
+}
+
+// repl reads messages from stdin until EOF or /bye.
+func (s *chatSession) repl(ctx context.Context) error {
+	scanner := bufio.NewScanner(s.e.stdin)
+	for {
+		fmt.Fprint(s.e.stderr, ">>> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(s.e.stderr)
+			return scanner.Err()
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "/") {
+			done, err := s.command(line)
+			if err != nil {
+				fmt.Fprintln(s.e.stderr, "error:", err)
+			}
+			if done {
+				return nil
+			}
+			continue
+		}
+
+		if err := s.send(ctx, line); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			fmt.Fprintln(s.e.stderr, "error:", err)
+		}
+	}
+}
+
+// command runs a REPL slash command and reports whether the REPL should exit.
+func (s *chatSession) command(line string) (bool, error) {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch name {
+	case "/bye", "/exit", "/quit":
+		return true, nil
+	case "/clear":
+		s.history = nil
+		if s.g.system != "" {
+			s.history = append(s.history, ollama.ChatMessage{Role: ollama.SystemRole, Content: s.g.system})
+		}
+	case "/history":
+		for _, m := range s.history {
+			fmt.Fprintf(s.e.stdout, "%s: %s\n", m.Role, m.Content)
+		}
+	case "/save":
+		if arg == "" {
+			return false, errors.New("usage: /save FILE")
+		}
+		return false, s.save(arg)
+	case "/load":
+		if arg == "" {
+			return false, errors.New("usage: /load FILE")
+		}
+		return false, s.load(arg)
+	case "/help", "/?":
+		fmt.Fprint(s.e.stderr, chatHelp)
+	default:
+		return false, fmt.Errorf("unknown command %s, try /help", name)
+	}
+	return false, nil
+}
+
+// send adds message to the history, prints the reply and records it.
+func (s *chatSession) send(ctx context.Context, message string) error {
+	req := s.req
+	req.Messages = append(append([]ollama.ChatMessage(nil), s.history...), ollama.ChatMessage{
+		Role:    ollama.UserRole,
+		Content: message,
+	})
+
+	var reply ollama.ChatMessage
+	if s.g.jsonOutput() {
+		resp, err := s.client.Chat(ctx, &req)
+		if err != nil {
+			return err
+		}
+		if err := printJSON(s.e.stdout, resp); err != nil {
+			return err
+		}
+		reply = resp.Message
+	} else {
+		stream, err := s.client.ChatStream(ctx, &req)
+		if err != nil {
+			return err
+		}
+		var content strings.Builder
+		for item := range stream {
+			if item.Error != nil {
+				fmt.Fprintln(s.e.stdout)
+				return item.Error
+			}
+			fmt.Fprint(s.e.stdout, item.ChatResponse.Message.Content)
+			content.WriteString(item.ChatResponse.Message.Content)
+		}
+		fmt.Fprintln(s.e.stdout)
+		reply = ollama.ChatMessage{Role: ollama.AssistantRole, Content: content.String()}
+	}
+
+	s.history = append(req.Messages, reply)
+	return nil
+}
+
+func (s *chatSession) save(path string) error {
+	data, err := json.MarshalIndent(s.history, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func (s *chatSession) load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var history []ollama.ChatMessage
+	if err := json.Unmarshal(data, &history); err != nil {
+		return fmt.Errorf("invalid history file %s: %w", path, err)
+	}
+	s.history = history
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	ollama "github.com/wiseinf/ollama-go"
+)
+
+func runEmbed(ctx context.Context, e *env, args []string) error {
+	fs, g := newFlagSet(e, "embed", "MODEL TEXT...", optionsFlag)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := g.validate(); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		return usageError(fs, "embed requires a model and text")
+	}
+
+	options, err := g.parseOptions()
+	if err != nil {
+		return err
+	}
+
+	resp, err := g.client().Embeddings(ctx, &ollama.EmbeddingRequest{
+		Model:   fs.Arg(0),
+		Prompt:  strings.Join(fs.Args()[1:], " "),
+		Options: options,
+	})
+	if err != nil {
+		return err
+	}
+
+	if g.jsonOutput() {
+		return printJSON(e.stdout, resp)
+	}
+	values := make([]string, len(resp.Embedding))
+	for i, v := range resp.Embedding {
+		values[i] = fmt.Sprint(v)
+	}
+	fmt.Fprintln(e.stdout, strings.Join(values, " "))
+	return nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	ollama "github.com/wiseinf/ollama-go"
+)
+
+// globalFlags holds the flags a command registers; host, format and debug
+// are shared by every command, the request flags only by those that apply them.
+type globalFlags struct {
+	host      string
+	format    string
+	options   string
+	system    string
+	keepAlive string
+	debug     bool
+
+	api *ollama.Client
+}
+
+// Request flags registered only by the commands that use them.
+const (
+	optionsFlag   = "options"
+	systemFlag    = "system"
+	keepAliveFlag = "keep-alive"
+)
+
+// newFlagSet creates a FlagSet for a command with the shared flags
+// registered, plus the request flags the command applies, so that a flag a
+// command would ignore is rejected instead.
+func newFlagSet(e *env, name, args string, requestFlags ...string) (*flag.FlagSet, *globalFlags) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(e.stderr, "Usage: ollama-go %s [flags] %s\n\nFlags:\n", name, args)
+		fs.PrintDefaults()
+	}
+
+	g := &globalFlags{}
+	fs.StringVar(&g.host, "host", "", "Ollama server address (default $OLLAMA_HOST or http://localhost:11434)")
+	fs.StringVar(&g.format, "format", "text", "output format: text or json")
+	fs.BoolVar(&g.debug, "debug", false, "log client activity")
+	for _, f := range requestFlags {
+		switch f {
+		case optionsFlag:
+			fs.StringVar(&g.options, optionsFlag, "", `model options as a JSON object or key=value pairs, e.g. "temperature=0,seed=42"`)
+		case systemFlag:
+			fs.StringVar(&g.system, systemFlag, "", "system prompt")
+		case keepAliveFlag:
+			fs.StringVar(&g.keepAlive, keepAliveFlag, "", `how long to keep the model loaded, e.g. "5m", or seconds; -1 keeps it loaded and 0 unloads it`)
+		}
+	}
+	return fs, g
+}
+
+// newClient builds an API client from the environment, the config file
+// and the flags.
+func (g *globalFlags) newClient() (*ollama.Client, error) {
+	var opts []ollama.ClientOption
+	if g.host != "" {
+		host, err := ollama.ParseHost(g.host)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, ollama.WithBaseURL(host))
+	}
+	if g.debug {
+		handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})
+		opts = append(opts, ollama.WithSlogLogger(slog.New(handler)))
+	} else {
+		opts = append(opts, ollama.WithLogger(quietLogger{}))
+	}
+	return ollama.NewClientFromEnv(opts...)
+}
+
+// client returns the API client built by validate.
+func (g *globalFlags) client() *ollama.Client {
+	return g.api
+}
+
+// quietLogger discards client logs so they do not mix with command output.
+type quietLogger struct{}
+
+func (quietLogger) Debug(format string, v ...interface{}) {}
+func (quietLogger) Info(format string, v ...interface{})  {}
+func (quietLogger) Error(format string, v ...interface{}) {}
+
+// jsonOutput reports whether machine-readable output was requested.
+func (g *globalFlags) jsonOutput() bool {
+	return g.format == "json"
+}
+
+// validate checks the flags and builds the API client.
+func (g *globalFlags) validate() error {
+	if g.format != "text" && g.format != "json" {
+		return fmt.Errorf("invalid format %q: want text or json", g.format)
+	}
+	client, err := g.newClient()
+	if err != nil {
+		return err
+	}
+	g.api = client
+	return nil
+}
+
+// parseOptions parses the --options flag. It accepts a JSON object, or
+// comma-separated key=value pairs whose values are decoded as JSON when
+// possible and kept as strings otherwise.
+func (g *globalFlags) parseOptions() (map[string]interface{}, error) {
+	s := strings.TrimSpace(g.options)
+	if s == "" {
+		return nil, nil
+	}
+
+	opts := make(map[string]interface{})
+	if strings.HasPrefix(s, "{") {
+		if err := json.Unmarshal([]byte(s), &opts); err != nil {
+			return nil, fmt.Errorf("invalid options: %w", err)
+		}
+		return opts, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid option %q: want key=value", pair)
+		}
+		value = strings.TrimSpace(value)
+
+		var v interface{}
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			v = value
+		}
+		opts[key] = v
+	}
+	return opts, nil
+}
+
+// parseKeepAlive parses the --keep-alive flag: a duration such as "5m", or
+// a number of seconds as the server accepts, where -1 keeps the model
+// loaded and 0 unloads it.
+func (g *globalFlags) parseKeepAlive() (ollama.Duration, error) {
+	var d ollama.Duration
+	s := strings.TrimSpace(g.keepAlive)
+	if s == "" {
+		return d, nil
+	}
+	b := []byte(s)
+	if _, err := strconv.ParseFloat(s, 64); err != nil {
+		b, _ = json.Marshal(s)
+	}
+	if err := d.UnmarshalJSON(b); err != nil {
+		return d, fmt.Errorf("invalid keep-alive %q: %w", g.keepAlive, err)
+	}
+	return d, nil
+}
+
+// printJSON writes v as a single line of JSON.
+func printJSON(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	ollama "github.com/wiseinf/ollama-go"
+)
+
+func runGenerate(ctx context.Context, e *env, args []string) error {
+	fs, g := newFlagSet(e, "generate", "MODEL [PROMPT]", optionsFlag, systemFlag, keepAliveFlag)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := g.validate(); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		return usageError(fs, "generate requires a model")
+	}
+
+	prompt := strings.Join(fs.Args()[1:], " ")
+	if prompt == "" {
+		var err error
+		if prompt, err = readAll(e.stdin); err != nil {
+			return fmt.Errorf("failed to read prompt: %w", err)
+		}
+	}
+
+	options, err := g.parseOptions()
+	if err != nil {
+		return err
+	}
+	keepAlive, err := g.parseKeepAlive()
+	if err != nil {
+		return err
+	}
+
+	req := &ollama.GenerateRequest{
+		Model:     fs.Arg(0),
+		Prompt:    prompt,
+		System:    g.system,
+		Options:   options,
+		KeepAlive: keepAlive,
+	}
+	client := g.client()
+
+	if g.jsonOutput() {
+		resp, err := client.Generate(ctx, req)
+		if err != nil {
+			return err
+		}
+		return printJSON(e.stdout, resp)
+	}
+
+	stream, err := client.GenerateStream(ctx, req)
+	if err != nil {
+		return err
+	}
+	for item := range stream {
+		if item.Error != nil {
+			return item.Error
+		}
+		fmt.Fprint(e.stdout, item.GenerateResponse.Response)
+	}
+	fmt.Fprintln(e.stdout)
+	return nil
+}
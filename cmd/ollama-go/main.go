@@ -0,0 +1,105 @@
+// Command ollama-go is a command-line client for the Ollama API built on
+// github.com/wiseinf/ollama-go. It covers the same ground as the official
+// CLI's client commands and can emit machine-readable JSON output.
+//
+// Usage:
+//
+//	ollama-go <command> [flags] [args]
+//
+// Flags must come before positional arguments. Run a command with -h to see
+// its flags.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+)
+
+const usage = `Usage: ollama-go <command> [flags] [args]
+
+Commands:
+  generate MODEL [PROMPT]      Generate a completion (reads stdin without PROMPT)
+  chat MODEL [MESSAGE]         Chat with a model (interactive without MESSAGE)
+  embed MODEL TEXT...          Generate embeddings
+  batch INPUT OUTPUT           Run a JSONL file of requests, writing JSONL results
+  bench MODEL...               Benchmark time to first token and throughput
+  models list                  List local models
+  models ps                    List running models
+  models show MODEL            Show model information
+  models pull MODEL            Pull a model from a registry
+  models push MODEL            Push a model to a registry
+  models copy SOURCE DEST      Copy a model
+  models delete MODEL          Delete a model
+
+Environment:
+  OLLAMA_HOST                  Server address when -host is not given
+  OLLAMA_CONFIG                Config file with client settings and profiles
+  OLLAMA_PROFILE               Config file profile to use
+`
+
+// command runs a subcommand with its remaining arguments.
+type command func(ctx context.Context, env *env, args []string) error
+
+// env carries the process streams so commands can be tested.
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+var commands = map[string]command{
+	"generate": runGenerate,
+	"chat":     runChat,
+	"embed":    runEmbed,
+	"batch":    runBatch,
+	"bench":    runBench,
+	"models":   runModels,
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	e := &env{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	if err := run(ctx, e, os.Args[1:]); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "error:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, e *env, args []string) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		fmt.Fprint(e.stdout, usage)
+		return nil
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprint(e.stderr, usage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	return cmd(ctx, e, args[1:])
+}
+
+// usageError reports a missing or malformed positional argument.
+func usageError(fs *flag.FlagSet, format string, v ...interface{}) error {
+	fs.Usage()
+	return fmt.Errorf(format, v...)
+}
+
+// readAll reads a prompt from r, trimming the trailing newline.
+func readAll(r io.Reader) (string, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	ollama "github.com/wiseinf/ollama-go"
+	"github.com/wiseinf/ollama-go/ollamatest"
+)
+
+// runCLI runs the CLI against server and returns stdout.
+func runCLI(t *testing.T, server *ollamatest.Server, stdin string, args ...string) (string, error) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	e := &env{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr}
+	n := 1
+	if args[0] == "models" {
+		n = 2
+	}
+	args = append(args[:n:n], append([]string{"-host", server.URL}, args[n:]...)...)
+	err := run(context.Background(), e, args)
+	return stdout.String(), err
+}
+
+func TestGenerateCommand(t *testing.T) {
+	server := ollamatest.NewServer()
+	defer server.Close()
+
+	out, err := runCLI(t, server, "", "generate", "-system", "be brief", "-options", "temperature=0,seed=42", "llama3.2:1b", "Hello")
+	if err != nil {
+		t.Fatalf("generate error = %v", err)
+	}
+	if want := server.Reply + "\n"; out != want {
+		t.Errorf("generate output = %q, want %q", out, want)
+	}
+
+	var body map[string]interface{}
+	json.Unmarshal(server.Bodies("/api/generate")[0], &body)
+	if body["system"] != "be brief" {
+		t.Errorf("request system = %v, want %q", body["system"], "be brief")
+	}
+	if want := map[string]interface{}{"temperature": float64(0), "seed": float64(42)}; !reflect.DeepEqual(body["options"], want) {
+		t.Errorf("request options = %v, want %v", body["options"], want)
+	}
+}
+
+func TestHostFromEnvironment(t *testing.T) {
+	server := ollamatest.NewServer()
+	defer server.Close()
+	t.Setenv("OLLAMA_CONFIG", "")
+	t.Setenv("OLLAMA_HOST", strings.TrimPrefix(server.URL, "http://"))
+
+	var stdout bytes.Buffer
+	e := &env{stdin: strings.NewReader(""), stdout: &stdout, stderr: io.Discard}
+	if err := run(context.Background(), e, []string{"generate", "llama3.2:1b", "Hello"}); err != nil {
+		t.Fatalf("generate error = %v", err)
+	}
+	if server.Hits("/api/generate") != 1 {
+		t.Errorf("server hits = %d, want the request sent to OLLAMA_HOST", server.Hits("/api/generate"))
+	}
+
+	t.Setenv("OLLAMA_HOST", "ftp://example.com")
+	if err := run(context.Background(), e, []string{"generate", "llama3.2:1b", "Hello"}); err == nil {
+		t.Error("generate with invalid OLLAMA_HOST error = nil")
+	}
+}
+
+func TestGenerateCommandJSON(t *testing.T) {
+	server := ollamatest.NewServer()
+	defer server.Close()
+
+	out, err := runCLI(t, server, "Hello from stdin", "generate", "-format", "json", "llama3.2:1b")
+	if err != nil {
+		t.Fatalf("generate error = %v", err)
+	}
+	var resp struct {
+		Response string `json:"response"`
+		Done     bool   `json:"done"`
+	}
+	if err := json.Unmarshal([]byte(out), &resp); err != nil {
+		t.Fatalf("generate output is not JSON: %v\n%s", err, out)
+	}
+	if resp.Response != server.Reply || !resp.Done {
+		t.Errorf("generate output = %+v", resp)
+	}
+}
+
+func TestChatREPL(t *testing.T) {
+	server := ollamatest.NewServer()
+	defer server.Close()
+	history := filepath.Join(t.TempDir(), "history.json")
+
+	input := "Hi\n/history\nHow are you?\n/bye\n"
+	out, err := runCLI(t, server, input, "chat", "-history", history, "llama3.2:1b")
+	if err != nil {
+		t.Fatalf("chat error = %v", err)
+	}
+	if !strings.Contains(out, "user: Hi\nassistant: "+server.Reply) {
+		t.Errorf("chat /history output missing first turn:\n%s", out)
+	}
+
+	bodies := server.Bodies("/api/chat")
+	if len(bodies) != 2 {
+		t.Fatalf("chat requests = %d, want 2", len(bodies))
+	}
+	var second struct {
+		Messages []map[string]string `json:"messages"`
+	}
+	json.Unmarshal(bodies[1], &second)
+	if len(second.Messages) != 3 {
+		t.Errorf("second chat request sent %d messages, want 3", len(second.Messages))
+	}
+
+	// The saved history is picked up by the next session.
+	if _, err := runCLI(t, server, "", "chat", "-history", history, "llama3.2:1b", "And now?"); err != nil {
+		t.Fatalf("chat error = %v", err)
+	}
+	json.Unmarshal(server.Bodies("/api/chat")[2], &second)
+	if len(second.Messages) != 5 {
+		t.Errorf("resumed chat request sent %d messages, want 5", len(second.Messages))
+	}
+}
+
+func TestModelsCommands(t *testing.T) {
+	server := ollamatest.NewServer()
+	defer server.Close()
+	server.Models = []ollamatest.Model{{Name: "llama3.2:1b", Size: 1300000000, Digest: "a80c4f17acd55265feec"}}
+
+	out, err := runCLI(t, server, "", "models", "list")
+	if err != nil {
+		t.Fatalf("models list error = %v", err)
+	}
+	if !strings.Contains(out, "llama3.2:1b") || !strings.Contains(out, "1.3 GB") || !strings.Contains(out, "a80c4f17acd5 ") {
+		t.Errorf("models list output = %q", out)
+	}
+
+	out, err = runCLI(t, server, "", "models", "pull", "-format", "json", "llama3.2:1b")
+	if err != nil {
+		t.Fatalf("models pull error = %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 3 {
+		t.Errorf("models pull output = %q, want 3 JSON lines", out)
+	}
+
+	server.Inject("/api/pull", ollamatest.Fault{ErrorAt: 2, ErrorMessage: "pull model manifest: file does not exist"})
+	if _, err := runCLI(t, server, "", "models", "pull", "llama3.2:1b"); err == nil || !strings.Contains(err.Error(), "file does not exist") {
+		t.Errorf("models pull error = %v, want the stream error", err)
+	}
+
+	out, err = runCLI(t, server, "", "models", "show", "llama3.2:1b")
+	if err != nil {
+		t.Fatalf("models show error = %v", err)
+	}
+	for _, want := range []string{"architecture", "131072", "Capabilities:", "completion"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("models show output = %q, want %q", out, want)
+		}
+	}
+
+	if _, err := runCLI(t, server, "", "models", "delete", "llama3.2:1b"); err != nil {
+		t.Errorf("models delete error = %v", err)
+	}
+	if _, err := runCLI(t, server, "", "models", "show", "missing"); err == nil {
+		t.Error("models show of a missing model succeeded")
+	}
+}
+
+func TestRunningModelColumns(t *testing.T) {
+	tests := []struct {
+		model         ollama.RunningModel
+		wantProcessor string
+		wantUntil     string
+	}{
+		{ollama.RunningModel{Size: 100, SizeVRAM: 100, ExpiresAt: time.Now().Add(5*time.Minute + 400*time.Millisecond)}, "100% GPU", "5m0s"},
+		{ollama.RunningModel{Size: 100, ExpiresAt: time.Now().Add(-time.Second)}, "100% CPU", "stopping"},
+		{ollama.RunningModel{Size: 100, SizeVRAM: 75, ExpiresAt: time.Now().AddDate(300, 0, 0)}, "25%/75% CPU/GPU", "forever"},
+		{ollama.RunningModel{}, "-", "-"},
+	}
+	for _, tt := range tests {
+		if got := processor(tt.model); got != tt.wantProcessor {
+			t.Errorf("processor(%+v) = %q, want %q", tt.model, got, tt.wantProcessor)
+		}
+		if got := until(tt.model); got != tt.wantUntil {
+			t.Errorf("until(%+v) = %q, want %q", tt.model, got, tt.wantUntil)
+		}
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{in: "", want: nil},
+		{in: `{"temperature": 0.5, "stop": ["\n"]}`, want: map[string]interface{}{"temperature": 0.5, "stop": []interface{}{"\n"}}},
+		{in: "temperature=0.5, num_ctx=4096, mirostat=true", want: map[string]interface{}{"temperature": 0.5, "num_ctx": float64(4096), "mirostat": true}},
+		{in: "stop=END", want: map[string]interface{}{"stop": "END"}},
+		{in: "temperature", wantErr: true},
+		{in: "{bad json", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		g := &globalFlags{options: tt.in}
+		got, err := g.parseOptions()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseOptions(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseOptions(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseKeepAlive(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ollama.Duration
+		wantErr bool
+	}{
+		{in: "", want: 0},
+		{in: "5m", want: ollama.Duration(5 * time.Minute)},
+		{in: "300", want: ollama.Duration(5 * time.Minute)},
+		{in: "-1", want: ollama.KeepForever},
+		{in: "0", want: ollama.UnloadNow},
+		{in: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		g := &globalFlags{keepAlive: tt.in}
+		got, err := g.parseKeepAlive()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseKeepAlive(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseKeepAlive(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestUnusedRequestFlagsRejected(t *testing.T) {
+	server := ollamatest.NewServer()
+	defer server.Close()
+
+	tests := [][]string{
+		{"bench", "-system", "be brief", "llama3.2:1b"},
+		{"bench", "-keep-alive", "5m", "llama3.2:1b"},
+		{"batch", "-options", "temperature=0", "in.jsonl", "out.jsonl"},
+		{"batch", "-system", "be brief", "in.jsonl", "out.jsonl"},
+		{"batch", "-keep-alive", "5m", "in.jsonl", "out.jsonl"},
+	}
+	for _, args := range tests {
+		if _, err := runCLI(t, server, "", args...); err == nil || !strings.Contains(err.Error(), "flag provided but not defined") {
+			t.Errorf("%v error = %v, want the flag rejected", args, err)
+		}
+	}
+	if server.Hits("/api/generate") != 0 {
+		t.Errorf("generate hits = %d, want none", server.Hits("/api/generate"))
+	}
+}
+
+func TestBatchCommand(t *testing.T) {
+	server := ollamatest.NewServer()
+	defer server.Close()
+
+	dir := t.TempDir()
+	in := filepath.Join(dir, "jobs.jsonl")
+	out := filepath.Join(dir, "results.jsonl")
+	os.WriteFile(in, []byte(`{"id": "1", "request": {"model": "llama3.2:1b", "prompt": "Hi"}}`+"\n"), 0o644)
+
+	for _, want := range []string{"succeeded 1", "skipped 1"} {
+		stdout, err := runCLI(t, server, "", "batch", in, out)
+		if err != nil {
+			t.Fatalf("batch error = %v", err)
+		}
+		if !strings.Contains(stdout, want) {
+			t.Errorf("batch output = %q, want %q", stdout, want)
+		}
+	}
+}
+
+func TestBenchCommand(t *testing.T) {
+	server := ollamatest.NewServer()
+	defer server.Close()
+
+	out, err := runCLI(t, server, "", "bench", "-prompt", "a", "-prompt", "b", "-concurrency", "1,2", "-format", "json", "llama3.2:1b")
+	if err != nil {
+		t.Fatalf("bench error = %v", err)
+	}
+	var report struct {
+		Results []struct {
+			Concurrency int `json:"concurrency"`
+			Requests    int `json:"requests"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("bench output is not JSON: %v\n%s", err, out)
+	}
+	if len(report.Results) != 2 || report.Results[1].Concurrency != 2 || report.Results[1].Requests != 2 {
+		t.Errorf("bench results = %+v", report.Results)
+	}
+}
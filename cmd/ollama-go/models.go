@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	ollama "github.com/wiseinf/ollama-go"
+)
+
+var modelCommands = map[string]struct {
+	args string
+	narg int
+	run  func(ctx context.Context, e *env, g *globalFlags, fs *flag.FlagSet) error
+}{
+	"list":   {"", 0, modelsList},
+	"ps":     {"", 0, modelsPs},
+	"show":   {"MODEL", 1, modelsShow},
+	"pull":   {"MODEL", 1, modelsPull},
+	"push":   {"MODEL", 1, modelsPush},
+	"copy":   {"SOURCE DEST", 2, modelsCopy},
+	"delete": {"MODEL", 1, modelsDelete},
+}
+
+func runModels(ctx context.Context, e *env, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(e.stderr, usage)
+		return fmt.Errorf("models requires a subcommand")
+	}
+	sub, ok := modelCommands[args[0]]
+	if !ok {
+		fmt.Fprint(e.stderr, usage)
+		return fmt.Errorf("unknown models subcommand %q", args[0])
+	}
+
+	fs, g := newFlagSet(e, "models "+args[0], sub.args)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if err := g.validate(); err != nil {
+		return err
+	}
+	if fs.NArg() != sub.narg {
+		return usageError(fs, "models %s takes %d argument(s)", args[0], sub.narg)
+	}
+	return sub.run(ctx, e, g, fs)
+}
+
+func modelsList(ctx context.Context, e *env, g *globalFlags, fs *flag.FlagSet) error {
+	models, err := g.client().ListModels(ctx)
+	if err != nil {
+		return err
+	}
+	if g.jsonOutput() {
+		return printJSON(e.stdout, models)
+	}
+
+	w := tabwriter.NewWriter(e.stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDIGEST\tSIZE\tMODIFIED")
+	for _, m := range models {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", m.Name, shortDigest(m.Digest), humanSize(m.Size), humanTime(m.ModifiedAt))
+	}
+	return w.Flush()
+}
+
+func modelsPs(ctx context.Context, e *env, g *globalFlags, fs *flag.FlagSet) error {
+	models, err := g.client().ListRunningModels(ctx)
+	if err != nil {
+		return err
+	}
+	if g.jsonOutput() {
+		return printJSON(e.stdout, models)
+	}
+
+	w := tabwriter.NewWriter(e.stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDIGEST\tSIZE\tPROCESSOR\tUNTIL")
+	for _, m := range models {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", m.Name, shortDigest(m.Digest), humanSize(m.Size), processor(m), until(m))
+	}
+	return w.Flush()
+}
+
+func modelsShow(ctx context.Context, e *env, g *globalFlags, fs *flag.FlagSet) error {
+	info, err := g.client().ShowModel(ctx, fs.Arg(0), nil)
+	if err != nil {
+		return err
+	}
+	if g.jsonOutput() {
+		return printJSON(e.stdout, info)
+	}
+
+	var modified string
+	if !info.ModifiedAt.IsZero() {
+		modified = humanTime(info.ModifiedAt)
+	}
+	w := tabwriter.NewWriter(e.stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "Model:")
+	for _, row := range [][2]string{
+		{"architecture", info.Architecture()},
+		{"family", info.Details.Family},
+		{"parameters", info.Details.ParameterSize},
+		{"quantization", info.Details.QuantizationLevel},
+		{"format", info.Details.Format},
+		{"context length", positive(info.ContextLength())},
+		{"embedding length", positive(info.EmbeddingLength())},
+		{"modified", modified},
+	} {
+		if row[1] != "" {
+			fmt.Fprintf(w, "  %s\t%s\n", row[0], row[1])
+		}
+	}
+	if len(info.Capabilities) > 0 {
+		fmt.Fprintln(w, "\nCapabilities:")
+		for _, c := range info.Capabilities {
+			fmt.Fprintf(w, "  %s\n", c)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if info.Parameters != "" {
+		fmt.Fprintf(e.stdout, "\nParameters:\n%s\n", info.Parameters)
+	}
+	if info.System != "" {
+		fmt.Fprintf(e.stdout, "\nSystem:\n%s\n", info.System)
+	}
+	if info.License != "" {
+		fmt.Fprintf(e.stdout, "\nLicense:\n%s\n", info.License)
+	}
+	if info.Modelfile != "" {
+		fmt.Fprintf(e.stdout, "\nModelfile:\n%s\n", info.Modelfile)
+	}
+	return nil
+}
+
+func modelsPull(ctx context.Context, e *env, g *globalFlags, fs *flag.FlagSet) error {
+	progress, err := g.client().PullModel(ctx, &ollama.PullModelRequest{Name: fs.Arg(0)})
+	if err != nil {
+		return err
+	}
+	return printProgress(e, g, progress)
+}
+
+func modelsPush(ctx context.Context, e *env, g *globalFlags, fs *flag.FlagSet) error {
+	progress, err := g.client().PushModel(ctx, &ollama.PushModelRequest{Name: fs.Arg(0)})
+	if err != nil {
+		return err
+	}
+	return printProgress(e, g, progress)
+}
+
+func modelsCopy(ctx context.Context, e *env, g *globalFlags, fs *flag.FlagSet) error {
+	err := g.client().CopyModel(ctx, &ollama.CopyModelRequest{Source: fs.Arg(0), Destination: fs.Arg(1)})
+	if err != nil {
+		return err
+	}
+	return printStatus(e, g, fmt.Sprintf("copied %s to %s", fs.Arg(0), fs.Arg(1)))
+}
+
+func modelsDelete(ctx context.Context, e *env, g *globalFlags, fs *flag.FlagSet) error {
+	if err := g.client().DeleteModel(ctx, fs.Arg(0)); err != nil {
+		return err
+	}
+	return printStatus(e, g, "deleted "+fs.Arg(0))
+}
+
+// printProgress prints pull and push progress, one status per line, and
+// returns the error that ended the stream, if any.
+func printProgress(e *env, g *globalFlags, progress <-chan ollama.ModelResponse) error {
+	var last string
+	for p := range progress {
+		if p.Error != nil {
+			return p.Error
+		}
+		if g.jsonOutput() {
+			if err := printJSON(e.stdout, p); err != nil {
+				return err
+			}
+			continue
+		}
+		if p.Status != last {
+			fmt.Fprintln(e.stdout, p.Status)
+			last = p.Status
+		}
+	}
+	return nil
+}
+
+// printStatus reports the outcome of a command without a response body.
+func printStatus(e *env, g *globalFlags, status string) error {
+	if g.jsonOutput() {
+		return printJSON(e.stdout, map[string]string{"status": "success"})
+	}
+	fmt.Fprintln(e.stdout, status)
+	return nil
+}
+
+func shortDigest(digest string) string {
+	if len(digest) > 12 {
+		return digest[:12]
+	}
+	return digest
+}
+
+func humanSize(b int64) string {
+	const unit = 1000
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+// processor describes where a running model is loaded, e.g. "100% GPU"
+// or "25%/75% CPU/GPU".
+func processor(m ollama.RunningModel) string {
+	gpu := int(m.VRAMFraction()*100 + 0.5)
+	switch {
+	case m.Size <= 0:
+		return "-"
+	case gpu >= 100:
+		return "100% GPU"
+	case gpu <= 0:
+		return "100% CPU"
+	}
+	return fmt.Sprintf("%d%%/%d%% CPU/GPU", 100-gpu, gpu)
+}
+
+// until describes when an idle running model will be unloaded.
+func until(m ollama.RunningModel) string {
+	switch d := m.TimeUntilUnload(); {
+	case m.ExpiresAt.IsZero():
+		return "-"
+	case d > 100*365*24*time.Hour:
+		return "forever"
+	case d == 0:
+		return "stopping"
+	default:
+		return d.Round(time.Second).String()
+	}
+}
+
+// positive formats n, or returns "" if it is not known.
+func positive(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return fmt.Sprint(n)
+}
+
+func humanTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.DateTime)
+}
@@ -0,0 +1,455 @@
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultHost is the address of a local Ollama server.
+const DefaultHost = "http://localhost:11434"
+
+// ParseHost converts an OLLAMA_HOST style address to a base URL. Like the
+// official CLI it accepts bare hosts, host:port, IPv6 addresses with or
+// without brackets, and URLs with an http or https scheme. The port
+// defaults to 11434 without a scheme and to the scheme's port otherwise.
+// unix:///path/to/socket addresses are returned unchanged.
+// Unspecified addresses such as 0.0.0.0 map to the loopback address.
+func ParseHost(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return DefaultHost, nil
+	}
+
+	if socket, ok := strings.CutPrefix(s, "unix://"); ok {
+		if socket == "" {
+			return "", fmt.Errorf("invalid host %q: missing socket path", s)
+		}
+		return s, nil
+	}
+
+	scheme, hostport, ok := strings.Cut(s, "://")
+	defaultPort := "11434"
+	switch {
+	case !ok:
+		scheme, hostport = "http", s
+	case scheme == "http":
+		defaultPort = "80"
+	case scheme == "https":
+		defaultPort = "443"
+	default:
+		return "", fmt.Errorf("invalid host %q: unsupported scheme %q", s, scheme)
+	}
+
+	hostport, path, _ := strings.Cut(hostport, "/")
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host, port = strings.Trim(hostport, "[]"), defaultPort
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return "", fmt.Errorf("invalid host %q: bad port %q", s, port)
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip.IsUnspecified() {
+			ip = net.IPv4(127, 0, 0, 1)
+			if strings.Contains(host, ":") {
+				ip = net.IPv6loopback
+			}
+		}
+		host = ip.String()
+	} else if host == "" {
+		host = "127.0.0.1"
+	}
+
+	u := url.URL{Scheme: scheme, Host: net.JoinHostPort(host, port)}
+	if path != "" {
+		u.Path = "/" + strings.TrimSuffix(path, "/")
+	}
+	return u.String(), nil
+}
+
+// Profile holds client settings read from the environment or a config
+// file. Empty fields are left at their defaults.
+type Profile struct {
+	Host         string            `json:"host,omitempty"`
+	Timeout      string            `json:"timeout,omitempty"`
+	MaxRetries   *int              `json:"max_retries,omitempty"`
+	RetryWait    string            `json:"retry_wait,omitempty"`
+	RetryMaxWait string            `json:"retry_max_wait,omitempty"`
+	RateLimit    *int              `json:"rate_limit,omitempty"`
+	Debug        *bool             `json:"debug,omitempty"`
+	BearerToken  string            `json:"bearer_token,omitempty"`
+	Headers      map[string]string `json:"headers,omitempty"`
+}
+
+// merge returns p with the fields set in o overriding its own.
+func (p Profile) merge(o Profile) Profile {
+	if o.Host != "" {
+		p.Host = o.Host
+	}
+	if o.Timeout != "" {
+		p.Timeout = o.Timeout
+	}
+	if o.MaxRetries != nil {
+		p.MaxRetries = o.MaxRetries
+	}
+	if o.RetryWait != "" {
+		p.RetryWait = o.RetryWait
+	}
+	if o.RetryMaxWait != "" {
+		p.RetryMaxWait = o.RetryMaxWait
+	}
+	if o.RateLimit != nil {
+		p.RateLimit = o.RateLimit
+	}
+	if o.Debug != nil {
+		p.Debug = o.Debug
+	}
+	if o.BearerToken != "" {
+		p.BearerToken = o.BearerToken
+	}
+	if len(o.Headers) > 0 {
+		headers := make(map[string]string, len(p.Headers)+len(o.Headers))
+		for k, v := range p.Headers {
+			headers[k] = v
+		}
+		for k, v := range o.Headers {
+			headers[k] = v
+		}
+		p.Headers = headers
+	}
+	return p
+}
+
+// Options converts the profile to client options.
+func (p Profile) Options() ([]ClientOption, error) {
+	var opts []ClientOption
+	if p.Host != "" {
+		baseURL, err := ParseHost(p.Host)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithBaseURL(baseURL))
+	}
+
+	durations := []struct {
+		name  string
+		value string
+		opt   func(time.Duration) func(*ClientOptions)
+	}{
+		{"timeout", p.Timeout, WithTimeout},
+		{"retry_wait", p.RetryWait, WithRetryWaitTime},
+		{"retry_max_wait", p.RetryMaxWait, WithRetryMaxWaitTime},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", d.name, err)
+		}
+		opts = append(opts, d.opt(v))
+	}
+
+	if p.MaxRetries != nil {
+		opts = append(opts, WithMaxRetries(*p.MaxRetries))
+	}
+	if p.RateLimit != nil {
+		opts = append(opts, WithRateLimit(*p.RateLimit))
+	}
+	if p.Debug != nil {
+		opts = append(opts, WithDebug(*p.Debug))
+	}
+	if p.BearerToken != "" {
+		opts = append(opts, WithBearerToken(p.BearerToken))
+	}
+	for k, v := range p.Headers {
+		opts = append(opts, WithHeader(k, v))
+	}
+	return opts, nil
+}
+
+// Config is a client config file: settings shared by all profiles, an
+// optional default profile name, and named profiles.
+//
+// A JSON config looks like
+//
+//	{"timeout": "2m", "profile": "laptop", "profiles": {"laptop": {"host": "localhost"}}}
+//
+// and the same config in the TOML subset accepted by LoadConfig is
+//
+//	timeout = "2m"
+//	profile = "laptop"
+//
+//	[profiles.laptop]
+//	host = "localhost"
+type Config struct {
+	Profile
+	DefaultProfile string             `json:"profile,omitempty"`
+	Profiles       map[string]Profile `json:"profiles,omitempty"`
+}
+
+// LoadConfig reads a config file. Files ending in .json are parsed as JSON,
+// anything else as a TOML subset with string, integer and boolean values,
+// [profiles.NAME] tables and [profiles.NAME.headers] subtables.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	var cfg *Config
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		cfg = &Config{}
+		err = json.Unmarshal(data, cfg)
+	} else {
+		cfg, err = parseTOML(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Resolve returns the shared settings merged with the named profile, or with
+// the default profile if name is empty.
+func (c *Config) Resolve(name string) (Profile, error) {
+	if name == "" {
+		name = c.DefaultProfile
+	}
+	if name == "" {
+		return c.Profile, nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown profile %q", name)
+	}
+	return c.Profile.merge(p), nil
+}
+
+// parseTOML parses the TOML subset described at LoadConfig.
+func parseTOML(data []byte) (*Config, error) {
+	cfg := &Config{Profiles: make(map[string]Profile)}
+	fields := make(map[string]map[string]interface{})
+	section := ""
+	fields[section] = make(map[string]interface{})
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: malformed table header", n)
+			}
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if section != "profiles" && !strings.HasPrefix(section, "profiles.") && section != "headers" {
+				return nil, fmt.Errorf("line %d: unknown table %q", n, section)
+			}
+			if fields[section] == nil {
+				fields[section] = make(map[string]interface{})
+			}
+			continue
+		}
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", n)
+		}
+		key = strings.Trim(strings.TrimSpace(key), `"`)
+		value, err := parseTOMLValue(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		fields[section][key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Assemble a JSON document and decode it so both formats share the
+	// same field names and validation.
+	doc := fields[""]
+	for section, values := range fields {
+		switch {
+		case section == "" || section == "profiles":
+			continue
+		case section == "headers":
+			doc["headers"] = values
+			continue
+		}
+		name := strings.TrimPrefix(section, "profiles.")
+		profiles, _ := doc["profiles"].(map[string]interface{})
+		if profiles == nil {
+			profiles = make(map[string]interface{})
+			doc["profiles"] = profiles
+		}
+		if base, ok := strings.CutSuffix(name, ".headers"); ok {
+			profile, _ := profiles[base].(map[string]interface{})
+			if profile == nil {
+				profile = make(map[string]interface{})
+				profiles[base] = profile
+			}
+			profile["headers"] = values
+			continue
+		}
+		profile, _ := profiles[name].(map[string]interface{})
+		if profile == nil {
+			profile = make(map[string]interface{})
+			profiles[name] = profile
+		}
+		for k, v := range values {
+			profile[k] = v
+		}
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// stripComment removes a # comment that is not inside a quoted string.
+func stripComment(line string) string {
+	inString := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			if inString {
+				i++
+			}
+		case '"':
+			inString = !inString
+		case '#':
+			if !inString {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
+
+func parseTOMLValue(s string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		return strconv.Unquote(s)
+	case s == "true" || s == "false":
+		return s == "true", nil
+	}
+	if n, err := strconv.Atoi(strings.ReplaceAll(s, "_", "")); err == nil {
+		return n, nil
+	}
+	return nil, fmt.Errorf("unsupported value %q", s)
+}
+
+// envProfile reads settings from OLLAMA_* environment variables.
+func envProfile() (Profile, error) {
+	p := Profile{
+		Host:         os.Getenv("OLLAMA_HOST"),
+		Timeout:      os.Getenv("OLLAMA_TIMEOUT"),
+		RetryWait:    os.Getenv("OLLAMA_RETRY_WAIT"),
+		RetryMaxWait: os.Getenv("OLLAMA_RETRY_MAX_WAIT"),
+		BearerToken:  os.Getenv("OLLAMA_BEARER_TOKEN"),
+	}
+	ints := []struct {
+		name string
+		dst  **int
+	}{
+		{"OLLAMA_MAX_RETRIES", &p.MaxRetries},
+		{"OLLAMA_RATE_LIMIT", &p.RateLimit},
+	}
+	for _, i := range ints {
+		if v := os.Getenv(i.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return Profile{}, fmt.Errorf("invalid %s: %w", i.name, err)
+			}
+			*i.dst = &n
+		}
+	}
+	if v := os.Getenv("OLLAMA_DEBUG"); v != "" {
+		// Like the Ollama server, treat any value but a false one as on,
+		// e.g. OLLAMA_DEBUG=2
+		debug, err := strconv.ParseBool(v)
+		if err != nil {
+			debug = true
+		}
+		p.Debug = &debug
+	}
+	return p, nil
+}
+
+// defaultConfigPath returns the config file used when OLLAMA_CONFIG is not
+// set, or "" if there is none.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	for _, name := range []string{"config.toml", "config.json"} {
+		path := filepath.Join(dir, "ollama-go", name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// NewClientFromEnv creates a client configured from, in increasing order of
+// precedence, the config file named by OLLAMA_CONFIG (or
+// $XDG_CONFIG_HOME/ollama-go/config.toml or config.json if present) using
+// the profile named by OLLAMA_PROFILE, OLLAMA_* environment variables, and
+// options. The environment variables are OLLAMA_HOST, OLLAMA_TIMEOUT,
+// OLLAMA_MAX_RETRIES, OLLAMA_RETRY_WAIT, OLLAMA_RETRY_MAX_WAIT,
+// OLLAMA_RATE_LIMIT, OLLAMA_DEBUG and OLLAMA_BEARER_TOKEN.
+func NewClientFromEnv(options ...ClientOption) (*Client, error) {
+	path := os.Getenv("OLLAMA_CONFIG")
+	if path == "" {
+		path = defaultConfigPath()
+	}
+	return NewClientFromConfig(path, os.Getenv("OLLAMA_PROFILE"), options...)
+}
+
+// NewClientFromConfig creates a client from the named profile of the config
+// file at path, overridden by OLLAMA_* environment variables and options.
+// An empty path skips the config file.
+func NewClientFromConfig(path, profile string, options ...ClientOption) (*Client, error) {
+	var p Profile
+	if path != "" {
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		if p, err = cfg.Resolve(profile); err != nil {
+			return nil, err
+		}
+	} else if profile != "" {
+		return nil, fmt.Errorf("profile %q requested without a config file", profile)
+	}
+
+	env, err := envProfile()
+	if err != nil {
+		return nil, err
+	}
+	opts, err := p.merge(env).Options()
+	if err != nil {
+		return nil, err
+	}
+	client := NewClient(append(opts, options...)...)
+	if client.err != nil {
+		return nil, client.err
+	}
+	return client, nil
+}
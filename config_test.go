@@ -0,0 +1,164 @@
+package ollama
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/wiseinf/ollama-go/ollamatest"
+)
+
+func TestParseHost(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", "http://localhost:11434"},
+		{"localhost", "http://localhost:11434"},
+		{"example.com:8080", "http://example.com:8080"},
+		{"0.0.0.0", "http://127.0.0.1:11434"},
+		{":11435", "http://127.0.0.1:11435"},
+		{"10.0.0.5:11434", "http://10.0.0.5:11434"},
+		{"::1", "http://[::1]:11434"},
+		{"[::1]", "http://[::1]:11434"},
+		{"[::]:8080", "http://[::1]:8080"},
+		{"[fe80::1]:8080", "http://[fe80::1]:8080"},
+		{"http://example.com", "http://example.com:80"},
+		{"https://example.com", "https://example.com:443"},
+		{"https://example.com:8443/ollama/", "https://example.com:8443/ollama"},
+		{" gpu-01:11434 ", "http://gpu-01:11434"},
+		{"unix:///run/ollama.sock", "unix:///run/ollama.sock"},
+	}
+	for _, tt := range tests {
+		got, err := ParseHost(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseHost(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"ftp://example.com", "unix://", "example.com:99999", "example.com:port"} {
+		if got, err := ParseHost(in); err == nil {
+			t.Errorf("ParseHost(%q) = %q, want error", in, got)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	toml := `# shared settings
+timeout = "2m"
+max_retries = 5
+profile = "laptop"
+
+[profiles.laptop]
+host = "localhost"
+
+[profiles.cluster]
+host = "https://ollama.svc:8443" # behind the proxy
+max_retries = 1
+debug = true
+
+[profiles.cluster.headers]
+X-Team = "ml"
+`
+	json := `{
+  "timeout": "2m",
+  "max_retries": 5,
+  "profile": "laptop",
+  "profiles": {
+    "laptop": {"host": "localhost"},
+    "cluster": {"host": "https://ollama.svc:8443", "max_retries": 1, "debug": true, "headers": {"X-Team": "ml"}}
+  }
+}`
+	one, five, yes := 1, 5, true
+	wantLaptop := Profile{Host: "localhost", Timeout: "2m", MaxRetries: &five}
+	wantCluster := Profile{Host: "https://ollama.svc:8443", Timeout: "2m", MaxRetries: &one, Debug: &yes, Headers: map[string]string{"X-Team": "ml"}}
+
+	for name, content := range map[string]string{"config.toml": toml, "config.json": json} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			t.Fatalf("LoadConfig(%s) error = %v", name, err)
+		}
+		if got, err := cfg.Resolve(""); err != nil || !reflect.DeepEqual(got, wantLaptop) {
+			t.Errorf("%s: Resolve(\"\") = %+v, %v, want %+v", name, got, err, wantLaptop)
+		}
+		if got, err := cfg.Resolve("cluster"); err != nil || !reflect.DeepEqual(got, wantCluster) {
+			t.Errorf("%s: Resolve(cluster) = %+v, %v, want %+v", name, got, err, wantCluster)
+		}
+		if _, err := cfg.Resolve("missing"); err == nil {
+			t.Errorf("%s: Resolve(missing) error = nil", name)
+		}
+	}
+
+	bad := filepath.Join(dir, "bad.toml")
+	os.WriteFile(bad, []byte("[servers]\nhost = \"x\"\n"), 0o644)
+	if _, err := LoadConfig(bad); err == nil {
+		t.Error("LoadConfig() with unknown table error = nil")
+	}
+}
+
+func TestEnvProfileDebug(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"1", true},
+		{"true", true},
+		{"2", true},
+		{"yes", true},
+		{"0", false},
+		{"false", false},
+		{"FALSE", false},
+	}
+	for _, tt := range tests {
+		t.Setenv("OLLAMA_DEBUG", tt.value)
+		p, err := envProfile()
+		if err != nil {
+			t.Fatalf("envProfile() with OLLAMA_DEBUG=%s error = %v", tt.value, err)
+		}
+		if p.Debug == nil || *p.Debug != tt.want {
+			t.Errorf("OLLAMA_DEBUG=%s: Debug = %v, want %v", tt.value, p.Debug, tt.want)
+		}
+	}
+}
+
+func TestNewClientFromEnv(t *testing.T) {
+	server := ollamatest.NewServer()
+	defer server.Close()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	config := "[profiles.test]\nhost = \"unreachable.invalid\"\nretry_wait = \"5s\"\nmax_retries = 7\n"
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("OLLAMA_CONFIG", path)
+	t.Setenv("OLLAMA_PROFILE", "test")
+	t.Setenv("OLLAMA_HOST", server.URL[len("http://"):])
+	t.Setenv("OLLAMA_TIMEOUT", "45s")
+	client, err := NewClientFromEnv(WithMaxRetries(2))
+	if err != nil {
+		t.Fatalf("NewClientFromEnv() error = %v", err)
+	}
+	if client.baseURL != server.URL {
+		t.Errorf("baseURL = %s, want %s from OLLAMA_HOST", client.baseURL, server.URL)
+	}
+	if client.opts.RetryWaitTime != 5*time.Second || client.opts.MaxRetries != 2 || client.opts.Timeout != 45*time.Second {
+		t.Errorf("options = %+v, want config, env and explicit options applied in order", client.opts)
+	}
+	if _, err := client.ListModels(context.Background()); err != nil {
+		t.Errorf("ListModels() error = %v", err)
+	}
+
+	t.Setenv("OLLAMA_MAX_RETRIES", "many")
+	if _, err := NewClientFromEnv(); err == nil {
+		t.Error("NewClientFromEnv() with invalid OLLAMA_MAX_RETRIES error = nil")
+	}
+}
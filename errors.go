@@ -1,6 +1,11 @@
 package ollama
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
 
 // APIError represents an error returned by the Ollama API
 type APIError struct {
@@ -11,3 +16,13 @@ type APIError struct {
 func (e *APIError) Error() string {
 	return fmt.Sprintf("ollama api error: %s (status code: %d)", e.Message, e.StatusCode)
 }
+
+// IsModelNotFound reports whether err means the requested model does not
+// exist on the server.
+func IsModelNotFound(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusNotFound || strings.Contains(apiErr.Message, "not found")
+}
@@ -68,7 +68,8 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	fmt.Printf("Model info: %+v\n", modelInfo)
+	fmt.Printf("Model: %s %s, context length: %d, tools: %v\n",
+		modelInfo.Details.Family, modelInfo.Details.ParameterSize, modelInfo.ContextLength(), modelInfo.SupportsTools())
 
 	// List running model
 	runningModels, err := client.ListRunningModels(ctx)
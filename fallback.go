@@ -0,0 +1,172 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// FallbackPolicy reports whether a call that failed with err on model
+// should be retried with the next model in the chain.
+type FallbackPolicy func(model string, err error) bool
+
+// DefaultFallbackPolicy falls back when the model may answer elsewhere in
+// the chain: model not found, timeouts, server errors, connection errors and
+// open circuits. Other errors, e.g. a 400 for an invalid request, would fail
+// the same way on every model and end the chain.
+func DefaultFallbackPolicy(model string, err error) bool {
+	if IsModelNotFound(err) || errors.Is(err, ErrCircuitOpen) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// FallbackOptions configures a Fallback
+type FallbackOptions struct {
+	Policy FallbackPolicy
+	// AttemptTimeout bounds the call to each model so that a slow model
+	// leaves time for the rest of the chain. Zero means no limit.
+	AttemptTimeout time.Duration
+}
+
+// FallbackOption is a function that modifies the fallback options
+type FallbackOption func(*FallbackOptions)
+
+// WithFallbackPolicy sets which errors move on to the next model
+func WithFallbackPolicy(policy FallbackPolicy) FallbackOption {
+	return func(o *FallbackOptions) {
+		o.Policy = policy
+	}
+}
+
+// WithAttemptTimeout bounds the call to each model in the chain
+func WithAttemptTimeout(d time.Duration) FallbackOption {
+	return func(o *FallbackOptions) {
+		o.AttemptTimeout = d
+	}
+}
+
+// Fallback sends Generate and Chat requests down a chain of models until
+// one answers, e.g. llama3.1:70b, then llama3.1:8b, then llama3.2:1b. The
+// Model field of the response names the model that answered.
+type Fallback struct {
+	api    API
+	models []string
+	opts   *FallbackOptions
+}
+
+// NewFallback creates a Fallback that calls api with models in order.
+func NewFallback(api API, models []string, options ...FallbackOption) *Fallback {
+	opts := &FallbackOptions{Policy: DefaultFallbackPolicy}
+	for _, opt := range options {
+		opt(opts)
+	}
+	return &Fallback{api: api, models: models, opts: opts}
+}
+
+// FallbackError is returned when every model in the chain failed.
+type FallbackError struct {
+	// Models are the models tried, in order, and Errors their errors.
+	Models []string
+	Errors []error
+}
+
+func (e *FallbackError) Error() string {
+	parts := make([]string, len(e.Models))
+	for i, m := range e.Models {
+		parts[i] = fmt.Sprintf("%s: %v", m, e.Errors[i])
+	}
+	return "all fallback models failed: " + strings.Join(parts, "; ")
+}
+
+// Unwrap returns the errors of the models tried.
+func (e *FallbackError) Unwrap() []error {
+	return e.Errors
+}
+
+// chain returns the models to try for a request naming model. A request
+// for a model in the chain starts there; any other model is tried before
+// the whole chain.
+func (f *Fallback) chain(model string) []string {
+	if model == "" {
+		return f.models
+	}
+	for i, m := range f.models {
+		if m == model {
+			return f.models[i:]
+		}
+	}
+	return append([]string{model}, f.models...)
+}
+
+// fallbackCall calls fn with each model of the chain until one succeeds or
+// the policy stops the chain.
+func fallbackCall[T any](ctx context.Context, f *Fallback, model string, fn func(ctx context.Context, model string) (T, error)) (T, error) {
+	var zero T
+	models := f.chain(model)
+	if len(models) == 0 {
+		return zero, errors.New("fallback chain has no models")
+	}
+
+	fallbackErr := &FallbackError{}
+	for i, m := range models {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if f.opts.AttemptTimeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, f.opts.AttemptTimeout)
+		}
+		result, err := fn(attemptCtx, m)
+		cancel()
+		if err == nil {
+			return result, nil
+		}
+
+		fallbackErr.Models = append(fallbackErr.Models, m)
+		fallbackErr.Errors = append(fallbackErr.Errors, err)
+		if ctx.Err() != nil || i == len(models)-1 || !f.opts.Policy(m, err) {
+			break
+		}
+	}
+	if len(fallbackErr.Errors) == 1 {
+		return zero, fallbackErr.Errors[0]
+	}
+	return zero, fallbackErr
+}
+
+// Generate sends req to the first model of the chain that answers
+func (f *Fallback) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	return fallbackCall(ctx, f, req.Model, func(ctx context.Context, model string) (*GenerateResponse, error) {
+		r := *req
+		r.Model = model
+		resp, err := f.api.Generate(ctx, &r)
+		if err == nil && resp.Model == "" {
+			resp.Model = model
+		}
+		return resp, err
+	})
+}
+
+// Chat sends req to the first model of the chain that answers
+func (f *Fallback) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	return fallbackCall(ctx, f, req.Model, func(ctx context.Context, model string) (*ChatResponse, error) {
+		r := *req
+		r.Model = model
+		resp, err := f.api.Chat(ctx, &r)
+		if err == nil && resp.Model == "" {
+			resp.Model = model
+		}
+		return resp, err
+	})
+}
@@ -0,0 +1,177 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/wiseinf/ollama-go/ollamatest"
+)
+
+// withFallbackModels answers generate and chat requests by model name:
+// "big" is not found, "slow" takes a second, "broken" fails, "strict"
+// rejects the request and any other model answers.
+func withFallbackModels() testServerOption {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		switch req.Model {
+		case "big":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `{"error":"model %q not found, try pulling it first"}`, req.Model)
+			return
+		case "slow":
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+				return
+			}
+		case "strict":
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"error":"invalid options"}`)
+			return
+		case "broken":
+			w.WriteHeader(http.StatusInternalServerError)
+			io.WriteString(w, `{"error":"llama runner process has terminated"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"model":%q,"response":"ok","message":{"role":"assistant","content":"ok"},"done":true}`, req.Model)
+	}
+	return func(o *testServerOptions) {
+		withHandler("/api/generate", handler)(o)
+		withHandler("/api/chat", handler)(o)
+	}
+}
+
+func requestedModels(server *ollamatest.Server, path string) []string {
+	var models []string
+	for _, body := range server.Bodies(path) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.Unmarshal(body, &req)
+		models = append(models, req.Model)
+	}
+	return models
+}
+
+func TestFallbackGenerate(t *testing.T) {
+	server, client := newTestServer(t, withFallbackModels(), withClientOptions(WithMaxRetries(0)))
+	fb := NewFallback(client, []string{"big", "slow", "broken", "small"}, WithAttemptTimeout(50*time.Millisecond))
+
+	req := &GenerateRequest{Prompt: "Hi"}
+	resp, err := fb.Generate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if resp.Model != "small" {
+		t.Errorf("answered by %q, want small", resp.Model)
+	}
+	if got, want := fmt.Sprint(requestedModels(server, "/api/generate")), "[big slow broken small]"; got != want {
+		t.Errorf("models tried = %s, want %s", got, want)
+	}
+	if req.Model != "" {
+		t.Errorf("request model changed to %q", req.Model)
+	}
+}
+
+func TestFallbackChat(t *testing.T) {
+	server, client := newTestServer(t, withFallbackModels(), withClientOptions(WithMaxRetries(0)))
+	fb := NewFallback(client, []string{"big", "medium", "small"})
+
+	// A request for a model in the chain starts at that model.
+	resp, err := fb.Chat(context.Background(), &ChatRequest{Model: "medium", Messages: []ChatMessage{{Role: UserRole, Content: "Hi"}}})
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if resp.Model != "medium" || resp.Message.Content != "ok" {
+		t.Errorf("response = %+v, want an answer from medium", resp)
+	}
+
+	// Any other model is tried before the chain.
+	resp, err = fb.Chat(context.Background(), &ChatRequest{Model: "broken", Messages: []ChatMessage{{Role: UserRole, Content: "Hi"}}})
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if resp.Model != "medium" {
+		t.Errorf("answered by %q, want medium", resp.Model)
+	}
+	if got, want := fmt.Sprint(requestedModels(server, "/api/chat")), "[medium broken big medium]"; got != want {
+		t.Errorf("models tried = %s, want %s", got, want)
+	}
+}
+
+func TestFallbackPolicy(t *testing.T) {
+	_, client := newTestServer(t, withFallbackModels(), withClientOptions(WithMaxRetries(0)))
+	onlyNotFound := func(model string, err error) bool { return IsModelNotFound(err) }
+	fb := NewFallback(client, []string{"big", "broken", "small"}, WithFallbackPolicy(onlyNotFound))
+
+	_, err := fb.Generate(context.Background(), &GenerateRequest{Prompt: "Hi"})
+	var fbErr *FallbackError
+	if !errors.As(err, &fbErr) || fmt.Sprint(fbErr.Models) != "[big broken]" {
+		t.Fatalf("Generate() error = %v, want a FallbackError for big and broken", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("errors.As(APIError) = %+v, want the first model's error", apiErr)
+	}
+	if !IsModelNotFound(fbErr.Errors[0]) || IsModelNotFound(fbErr.Errors[1]) {
+		t.Errorf("IsModelNotFound = %v, %v, want true, false", IsModelNotFound(fbErr.Errors[0]), IsModelNotFound(fbErr.Errors[1]))
+	}
+}
+
+func TestDefaultFallbackPolicy(t *testing.T) {
+	server, client := newTestServer(t, withFallbackModels(), withClientOptions(WithMaxRetries(0)))
+	fb := NewFallback(client, []string{"strict", "small"})
+
+	// A rejected request is not sent to the rest of the chain.
+	var apiErr *APIError
+	if _, err := fb.Generate(context.Background(), &GenerateRequest{Prompt: "Hi"}); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Generate() error = %v, want the 400", err)
+	}
+	if got := requestedModels(server, "/api/generate"); len(got) != 1 {
+		t.Errorf("models tried = %v, want only the first", got)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"not found", &APIError{StatusCode: http.StatusNotFound, Message: "model not found"}, true},
+		{"server error", fmt.Errorf("all retries failed: %w", &APIError{StatusCode: http.StatusServiceUnavailable}), true},
+		{"bad request", &APIError{StatusCode: http.StatusBadRequest, Message: "invalid options"}, false},
+		{"rate limited", &APIError{StatusCode: http.StatusTooManyRequests}, false},
+		{"timeout", fmt.Errorf("all retries failed: %w", context.DeadlineExceeded), true},
+		{"connection", &url.Error{Op: "Post", URL: "http://localhost:11434", Err: errors.New("connection refused")}, true},
+		{"open circuit", &CircuitOpenError{Host: "http://localhost:11434"}, true},
+		{"decode", errors.New("invalid character"), false},
+	}
+	for _, tt := range tests {
+		if got := DefaultFallbackPolicy("llama3.2", tt.err); got != tt.want {
+			t.Errorf("DefaultFallbackPolicy(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFallbackStopsOnCancel(t *testing.T) {
+	server, client := newTestServer(t, withFallbackModels(), withClientOptions(WithMaxRetries(0)))
+	fb := NewFallback(client, []string{"slow", "small"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := fb.Generate(ctx, &GenerateRequest{Prompt: "Hi"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Generate() error = %v, want the caller's deadline", err)
+	}
+	if got := requestedModels(server, "/api/generate"); len(got) != 1 {
+		t.Errorf("models tried = %v, want only the first", got)
+	}
+}
@@ -0,0 +1,209 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wiseinf/ollama-go/internal/stats"
+)
+
+// Hedging needs this many latency samples before a percentile delay is
+// used, and keeps at most hedgeWindow samples per endpoint and model.
+const (
+	hedgeMinSamples = 20
+	hedgeWindow     = 200
+)
+
+// WithHedgeDelay enables hedging of non-streaming Generate, Chat and
+// Embeddings calls: if a call has not been answered after d, a duplicate
+// is sent to another endpoint and the first answer wins. With
+// WithHedgePercentile, d is only used until enough latencies have been
+// observed.
+func WithHedgeDelay(d time.Duration) PoolOption {
+	return func(o *PoolOptions) {
+		o.HedgeDelay = d
+	}
+}
+
+// WithHedgePercentile enables hedging after the given percentile of
+// recently observed latencies for the same endpoint and model, e.g. 0.95.
+func WithHedgePercentile(p float64) PoolOption {
+	return func(o *PoolOptions) {
+		o.HedgePercentile = p
+	}
+}
+
+// HedgeStats counts hedged requests.
+type HedgeStats struct {
+	// Hedged is the number of duplicate requests sent.
+	Hedged int64
+	// Won is the number of calls answered by a duplicate request.
+	Won int64
+}
+
+// hedger holds the state used to decide when to hedge.
+type hedger struct {
+	mu        sync.Mutex
+	latencies map[string]*latencyWindow
+
+	hedged atomic.Int64
+	won    atomic.Int64
+}
+
+// latencyWindow is a ring of recent latencies.
+type latencyWindow struct {
+	samples []time.Duration
+	next    int
+}
+
+func (w *latencyWindow) add(d time.Duration) {
+	if len(w.samples) < hedgeWindow {
+		w.samples = append(w.samples, d)
+		return
+	}
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % hedgeWindow
+}
+
+func (w *latencyWindow) percentile(p float64) time.Duration {
+	sorted := append([]time.Duration(nil), w.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[stats.NearestRank(len(sorted), p)]
+}
+
+// observe records the latency of a call. When a hedge wins, the primary's
+// latency is only known to be at least its elapsed time, which is recorded
+// so that slow primaries keep the percentile from drifting down.
+func (h *hedger) observe(key string, d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	w, ok := h.latencies[key]
+	if !ok {
+		w = &latencyWindow{}
+		h.latencies[key] = w
+	}
+	w.add(d)
+}
+
+// HedgeStats returns the hedging counters.
+func (p *Pool) HedgeStats() HedgeStats {
+	return HedgeStats{Hedged: p.hedger.hedged.Load(), Won: p.hedger.won.Load()}
+}
+
+// hedgeDelay returns how long to wait before hedging a call, or 0 to not
+// hedge.
+func (p *Pool) hedgeDelay(key string) time.Duration {
+	if p.opts.HedgePercentile > 0 {
+		p.hedger.mu.Lock()
+		w, ok := p.hedger.latencies[key]
+		var d time.Duration
+		if ok && len(w.samples) >= hedgeMinSamples {
+			d = w.percentile(p.opts.HedgePercentile)
+		}
+		p.hedger.mu.Unlock()
+		if d > 0 {
+			return d
+		}
+	}
+	return p.opts.HedgeDelay
+}
+
+type hedgeResult[T any] struct {
+	ep      *poolEndpoint
+	result  T
+	err     error
+	latency time.Duration
+	hedge   bool
+}
+
+// hedgedCall runs fn on the best endpoint and, if it has not answered
+// within the hedge delay, on the next one too, returning the first
+// success and cancelling the other call. Endpoints that cannot be reached
+// are failed over immediately as in poolCall.
+func hedgedCall[T any](ctx context.Context, p *Pool, endpoint, model string, fn func(context.Context, *Client) (T, error)) (T, error) {
+	var zero T
+	key := endpoint + " " + model
+	delay := p.hedgeDelay(key)
+	if delay <= 0 || len(p.endpoints) < 2 {
+		return poolCall(ctx, p, model, func(c *Client) (T, error) {
+			start := time.Now()
+			result, err := fn(ctx, c)
+			if err == nil {
+				p.hedger.observe(key, time.Since(start))
+			}
+			return result, err
+		}, nil)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	eps := p.order(model)
+	results := make(chan hedgeResult[T], len(eps))
+	next, inFlight := 0, 0
+	hedged := false
+	var primaryStart time.Time
+	launch := func(hedge bool) {
+		ep := eps[next]
+		next++
+		inFlight++
+		ep.outstanding.Add(1)
+		start := time.Now()
+		if !hedge {
+			primaryStart = start
+		}
+		go func() {
+			defer ep.outstanding.Add(-1)
+			result, err := fn(ctx, ep.client)
+			results <- hedgeResult[T]{ep: ep, result: result, err: err, latency: time.Since(start), hedge: hedge}
+		}()
+	}
+
+	launch(false)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	var lastErr error
+	for inFlight > 0 {
+		select {
+		case <-timer.C:
+			if !hedged && next < len(eps) {
+				hedged = true
+				p.hedger.hedged.Add(1)
+				launch(true)
+			}
+		case r := <-results:
+			inFlight--
+			if r.err == nil {
+				p.succeeded(r.ep, model)
+				if r.hedge {
+					p.hedger.won.Add(1)
+					p.hedger.observe(key, time.Since(primaryStart))
+				} else {
+					p.hedger.observe(key, r.latency)
+				}
+				return r.result, nil
+			}
+			if ctx.Err() != nil {
+				return zero, r.err
+			}
+			lastErr = r.err
+			if !p.failover(ctx, r.ep, r.err) {
+				if inFlight == 0 {
+					return zero, r.err
+				}
+				continue
+			}
+			if next < len(eps) {
+				launch(r.hedge)
+				// A relaunched primary gets the full delay before it is hedged.
+				if !hedged {
+					timer.Reset(delay)
+				}
+			}
+		}
+	}
+	return zero, fmt.Errorf("all pool endpoints failed: %w", lastErr)
+}
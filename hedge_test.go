@@ -0,0 +1,114 @@
+package ollama
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPoolHedging(t *testing.T) {
+	pool, servers := newTestPool(t, 2, WithHedgeDelay(20*time.Millisecond))
+	cancelled := make(chan struct{}, 1)
+	servers[0].Handle("/api/generate", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2 * time.Second):
+			io.WriteString(w, `{"model":"llama3.2:1b","response":"slow","done":true}`)
+		case <-r.Context().Done():
+			cancelled <- struct{}{}
+		}
+	})
+
+	start := time.Now()
+	resp, err := pool.Generate(context.Background(), &GenerateRequest{Model: "llama3.2:1b", Prompt: "Hi"})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Generate() took %v, want the hedged request to answer", elapsed)
+	}
+	if resp.Response != servers[1].Reply {
+		t.Errorf("Response = %q, want the fast server's reply", resp.Response)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("slow request was not cancelled")
+	}
+	if got := pool.HedgeStats(); got != (HedgeStats{Hedged: 1, Won: 1}) {
+		t.Errorf("HedgeStats() = %+v, want one hedge won", got)
+	}
+	// The slow primary's latency is recorded as at least the hedge delay.
+	if w := pool.hedger.latencies["/api/generate llama3.2:1b"]; w == nil || len(w.samples) != 1 || w.samples[0] < 20*time.Millisecond {
+		t.Errorf("latencies = %+v, want the primary's elapsed time", w)
+	}
+
+	// Calls answered before the delay are not hedged.
+	if _, err := pool.Embeddings(context.Background(), &EmbeddingRequest{Model: "nomic-embed-text", Prompt: "Hi"}); err != nil {
+		t.Fatalf("Embeddings() error = %v", err)
+	}
+	if got := pool.HedgeStats().Hedged; got != 1 {
+		t.Errorf("hedged = %d after a fast call, want 1", got)
+	}
+	if hits := servers[0].Hits("/api/embeddings") + servers[1].Hits("/api/embeddings"); hits != 1 {
+		t.Errorf("embeddings hits = %d, want 1", hits)
+	}
+}
+
+func TestPoolHedgingFailover(t *testing.T) {
+	pool, servers := newTestPool(t, 3, WithHedgeDelay(time.Second))
+	servers[0].Close()
+
+	start := time.Now()
+	if _, err := pool.Chat(context.Background(), &ChatRequest{Model: "llama3.2:1b", Messages: []ChatMessage{{Role: UserRole, Content: "Hi"}}}); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Chat() took %v, want an immediate failover", elapsed)
+	}
+	if got := pool.HedgeStats().Hedged; got != 0 {
+		t.Errorf("hedged = %d, want a failover rather than a hedge", got)
+	}
+}
+
+func TestPoolHedgingFailoverResetsDelay(t *testing.T) {
+	// Every connection takes 200ms to dial, so the primary fails over at
+	// 200ms and its replacement answers at about 400ms, before a hedge
+	// delay of 300ms counted from the relaunch.
+	dialer := &net.Dialer{}
+	transport := &http.Transport{DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}}
+	pool, servers := newTestPool(t, 3, WithHedgeDelay(300*time.Millisecond),
+		WithClientOptions(WithHTTPClient(&http.Client{Transport: transport})))
+	servers[0].Close()
+
+	if _, err := pool.Generate(context.Background(), &GenerateRequest{Model: "llama3.2:1b", Prompt: "Hi"}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if got := pool.HedgeStats().Hedged; got != 0 {
+		t.Errorf("hedged = %d, want the delay restarted on failover", got)
+	}
+}
+
+func TestHedgeDelayPercentile(t *testing.T) {
+	pool, _ := newTestPool(t, 2, WithHedgeDelay(time.Second), WithHedgePercentile(0.9))
+	key := "/api/generate llama3.2:1b"
+	for i := 1; i < hedgeMinSamples; i++ {
+		pool.hedger.observe(key, time.Duration(i)*time.Millisecond)
+	}
+	if got := pool.hedgeDelay(key); got != time.Second {
+		t.Errorf("hedgeDelay() = %v before enough samples, want the fixed delay", got)
+	}
+	pool.hedger.observe(key, hedgeMinSamples*time.Millisecond)
+	if got := pool.hedgeDelay(key); got != 18*time.Millisecond {
+		t.Errorf("hedgeDelay() = %v, want the 90th percentile", got)
+	}
+}
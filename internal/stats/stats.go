@@ -0,0 +1,18 @@
+// Package stats holds statistics helpers shared by the client and its
+// tools.
+package stats
+
+import "math"
+
+// NearestRank returns the index of the p-th percentile, 0 < p <= 1, in a
+// sorted sample of n values using the nearest-rank method.
+func NearestRank(n int, p float64) int {
+	i := int(math.Ceil(p*float64(n))) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= n {
+		i = n - 1
+	}
+	return i
+}
@@ -0,0 +1,24 @@
+package stats
+
+import "testing"
+
+func TestNearestRank(t *testing.T) {
+	tests := []struct {
+		n    int
+		p    float64
+		want int
+	}{
+		{1, 0.5, 0},
+		{20, 0.5, 9},
+		{20, 0.9, 17},
+		{20, 0.99, 19},
+		{20, 1, 19},
+		{200, 0.95, 189},
+		{10, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := NearestRank(tt.n, tt.p); got != tt.want {
+			t.Errorf("NearestRank(%d, %v) = %d, want %d", tt.n, tt.p, got, tt.want)
+		}
+	}
+}
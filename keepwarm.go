@@ -0,0 +1,141 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// KeepWarmOptions configures a KeepWarm
+type KeepWarmOptions struct {
+	// Interval is how often the models are loaded again. A zero or negative
+	// interval uses the default, and an interval that is not shorter than a
+	// positive KeepAlive is cut to four fifths of it so that models are
+	// loaded again before they expire.
+	Interval time.Duration
+	// KeepAlive is the keep-alive sent with each load.
+	KeepAlive time.Duration
+	// UnloadOnStop unloads the models when the KeepWarm is stopped.
+	UnloadOnStop bool
+	// OnError is called when a model fails to load.
+	OnError func(model string, err error)
+}
+
+// KeepWarmOption is a function that modifies the keep-warm options
+type KeepWarmOption func(*KeepWarmOptions)
+
+// WithWarmInterval sets how often the models are loaded again
+func WithWarmInterval(d time.Duration) KeepWarmOption {
+	return func(o *KeepWarmOptions) {
+		o.Interval = d
+	}
+}
+
+// WithWarmKeepAlive sets the keep-alive sent with each load
+func WithWarmKeepAlive(d time.Duration) KeepWarmOption {
+	return func(o *KeepWarmOptions) {
+		o.KeepAlive = d
+	}
+}
+
+// WithUnloadOnStop unloads the models when the KeepWarm is stopped
+func WithUnloadOnStop(unload bool) KeepWarmOption {
+	return func(o *KeepWarmOptions) {
+		o.UnloadOnStop = unload
+	}
+}
+
+// WithWarmErrorHandler sets a function called when a model fails to load
+func WithWarmErrorHandler(fn func(model string, err error)) KeepWarmOption {
+	return func(o *KeepWarmOptions) {
+		o.OnError = fn
+	}
+}
+
+// KeepWarm keeps a set of models loaded by loading them periodically, so
+// that requests never wait for a cold start.
+type KeepWarm struct {
+	api    API
+	models []string
+	opts   *KeepWarmOptions
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+}
+
+// StartKeepWarm loads models through api now and then every interval
+// until Stop is called or ctx is done.
+func StartKeepWarm(ctx context.Context, api API, models []string, options ...KeepWarmOption) *KeepWarm {
+	opts := &KeepWarmOptions{
+		Interval:  4 * time.Minute,
+		KeepAlive: 5 * time.Minute,
+	}
+	for _, opt := range options {
+		opt(opts)
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 4 * time.Minute
+	}
+	if opts.KeepAlive > 0 && opts.Interval >= opts.KeepAlive {
+		opts.Interval = opts.KeepAlive - opts.KeepAlive/5
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	k := &KeepWarm{api: api, models: models, opts: opts, cancel: cancel, done: make(chan struct{})}
+	go k.run(ctx)
+	return k
+}
+
+func (k *KeepWarm) run(ctx context.Context) {
+	defer close(k.done)
+	ticker := time.NewTicker(k.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		k.warm(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// warm loads every model, in parallel.
+func (k *KeepWarm) warm(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, model := range k.models {
+		wg.Add(1)
+		go func(model string) {
+			defer wg.Done()
+			err := k.api.LoadModel(ctx, model, k.opts.KeepAlive)
+			if err != nil && ctx.Err() == nil && k.opts.OnError != nil {
+				k.opts.OnError(model, err)
+			}
+		}(model)
+	}
+	wg.Wait()
+}
+
+// Stop stops refreshing the models and waits for loads in flight to end.
+// With WithUnloadOnStop, the models are then unloaded using ctx.
+func (k *KeepWarm) Stop(ctx context.Context) error {
+	var err error
+	k.once.Do(func() {
+		k.cancel()
+		<-k.done
+		if !k.opts.UnloadOnStop {
+			return
+		}
+		var errs []error
+		for _, model := range k.models {
+			if e := k.api.UnloadModel(ctx, model); e != nil {
+				errs = append(errs, e)
+			}
+		}
+		err = errors.Join(errs...)
+	})
+	return err
+}
@@ -0,0 +1,122 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoadUnloadModel(t *testing.T) {
+	server, client := newTestServer(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+		want string
+	}{
+		{"server default", func() error { return client.LoadModel(ctx, "llama3.2:1b", 0) }, `{"model":"llama3.2:1b","stream":false}`},
+		{"duration", func() error { return client.LoadModel(ctx, "llama3.2:1b", 10*time.Minute) }, `{"model":"llama3.2:1b","stream":false,"keep_alive":"10m"}`},
+		{"forever", func() error { return client.LoadModel(ctx, "llama3.2:1b", -1) }, `{"model":"llama3.2:1b","stream":false,"keep_alive":-1}`},
+		{"unload", func() error { return client.UnloadModel(ctx, "llama3.2:1b") }, `{"model":"llama3.2:1b","stream":false,"keep_alive":0}`},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if got := strings.TrimSpace(string(server.Bodies("/api/generate")[i])); got != tt.want {
+				t.Errorf("request body = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if err := client.LoadModel(ctx, "", 0); err == nil {
+		t.Error("LoadModel() without a model succeeded")
+	}
+}
+
+func TestKeepWarm(t *testing.T) {
+	server, client := newTestServer(t, withClientOptions(WithMaxRetries(0)))
+
+	var mu sync.Mutex
+	failed := map[string]bool{}
+	k := StartKeepWarm(context.Background(), client, []string{"llama3.2:1b", "nomic-embed-text", ""},
+		WithWarmInterval(10*time.Millisecond),
+		WithWarmKeepAlive(time.Minute),
+		WithUnloadOnStop(true),
+		WithWarmErrorHandler(func(model string, err error) {
+			mu.Lock()
+			failed[model] = true
+			mu.Unlock()
+		}),
+	)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for server.Hits("/api/generate") < 9 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := k.Stop(context.Background()); err == nil {
+		t.Error("Stop() error = nil, want the unload error for the empty model")
+	}
+	hits := server.Hits("/api/generate")
+	if hits < 9 {
+		t.Fatalf("generate hits = %d, want models loaded repeatedly", hits)
+	}
+
+	mu.Lock()
+	if len(failed) != 1 || !failed[""] {
+		t.Errorf("failed models = %v, want only the empty model", failed)
+	}
+	mu.Unlock()
+
+	unloaded := map[string]bool{}
+	bodies := server.Bodies("/api/generate")
+	for _, body := range bodies[len(bodies)-3:] {
+		var req map[string]interface{}
+		json.Unmarshal(body, &req)
+		if req["keep_alive"] == float64(0) {
+			unloaded[req["model"].(string)] = true
+		}
+	}
+	if !unloaded["llama3.2:1b"] || !unloaded["nomic-embed-text"] {
+		t.Errorf("unloaded = %v, want both models unloaded on stop", unloaded)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if got := server.Hits("/api/generate"); got != hits {
+		t.Errorf("generate hits = %d after Stop, want %d", got, hits)
+	}
+	if err := k.Stop(context.Background()); err != nil {
+		t.Errorf("second Stop() error = %v", err)
+	}
+}
+
+func TestKeepWarmInterval(t *testing.T) {
+	_, client := newTestServer(t)
+
+	tests := []struct {
+		name    string
+		options []KeepWarmOption
+		want    time.Duration
+	}{
+		{"default", nil, 4 * time.Minute},
+		{"zero", []KeepWarmOption{WithWarmInterval(0)}, 4 * time.Minute},
+		{"negative", []KeepWarmOption{WithWarmInterval(-time.Second)}, 4 * time.Minute},
+		{"longer than keep-alive", []KeepWarmOption{WithWarmInterval(10 * time.Minute), WithWarmKeepAlive(time.Minute)}, 48 * time.Second},
+		{"zero with short keep-alive", []KeepWarmOption{WithWarmInterval(0), WithWarmKeepAlive(time.Minute)}, 48 * time.Second},
+		{"keep forever", []KeepWarmOption{WithWarmInterval(time.Hour), WithWarmKeepAlive(-1)}, time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := StartKeepWarm(context.Background(), client, []string{"llama3.2:1b"}, tt.options...)
+			defer k.Stop(context.Background())
+			if k.opts.Interval != tt.want {
+				t.Errorf("Interval = %v, want %v", k.opts.Interval, tt.want)
+			}
+		})
+	}
+}
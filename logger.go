@@ -1,9 +1,14 @@
 package ollama
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
+	"strconv"
+	"strings"
 )
 
 // Logger interface
@@ -22,8 +27,8 @@ type defaultLogger struct {
 
 func newDefaultLogger() *defaultLogger {
 	return &defaultLogger{
-		debug: log.New(os.Stdout, "[DEBUG] ", log.LstdFlags),
-		info:  log.New(os.Stdout, "[INFO] ", log.LstdFlags),
+		debug: log.New(os.Stderr, "[DEBUG] ", log.LstdFlags),
+		info:  log.New(os.Stderr, "[INFO] ", log.LstdFlags),
 		error: log.New(os.Stderr, "[ERROR] ", log.LstdFlags),
 	}
 }
@@ -39,3 +44,182 @@ func (l *defaultLogger) Info(format string, v ...interface{}) {
 func (l *defaultLogger) Error(format string, v ...interface{}) {
 	l.error.Output(2, fmt.Sprintf(format, v...))
 }
+
+// WithSlogLogger logs client activity to l with structured fields. Level
+// filtering is left to l's handler; WithDebug only applies to a Logger.
+func WithSlogLogger(l *slog.Logger) func(*ClientOptions) {
+	return func(o *ClientOptions) {
+		o.SlogLogger = l
+	}
+}
+
+// WithLogRedaction replaces prompts, messages and images in logged request
+// bodies with their size.
+func WithLogRedaction(redact bool) func(*ClientOptions) {
+	return func(o *ClientOptions) {
+		o.RedactLogs = redact
+	}
+}
+
+// newSlogLogger returns the structured logger the client logs to.
+func newSlogLogger(opts *ClientOptions) *slog.Logger {
+	if opts.SlogLogger != nil {
+		return opts.SlogLogger
+	}
+	if opts.Logger == nil {
+		return slog.New(discardHandler{})
+	}
+	return slog.New(&loggerHandler{logger: opts.Logger, debug: opts.Debug})
+}
+
+// loggerHandler is a slog.Handler that writes records to a printf-style
+// Logger as a message followed by key=value pairs. Debug records are
+// dropped unless debug is set.
+type loggerHandler struct {
+	logger Logger
+	debug  bool
+	attrs  []slog.Attr
+	group  string
+}
+
+func (h *loggerHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelInfo || h.debug
+}
+
+func (h *loggerHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	b.WriteString(r.Message)
+	for _, a := range h.attrs {
+		appendAttr(&b, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		appendAttr(&b, h.group, a)
+		return true
+	})
+
+	switch {
+	case r.Level >= slog.LevelError:
+		h.logger.Error("%s", b.String())
+	case r.Level >= slog.LevelInfo:
+		h.logger.Info("%s", b.String())
+	default:
+		h.logger.Debug("%s", b.String())
+	}
+	return nil
+}
+
+func (h *loggerHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	h2.attrs = append(h2.attrs, h.attrs...)
+	for _, a := range attrs {
+		if h.group != "" {
+			a.Key = h.group + "." + a.Key
+		}
+		h2.attrs = append(h2.attrs, a)
+	}
+	return &h2
+}
+
+func (h *loggerHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	if h.group != "" {
+		name = h.group + "." + name
+	}
+	h2.group = name
+	return &h2
+}
+
+// appendAttr writes a as " key=value", flattening groups.
+func appendAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	key := a.Key
+	if prefix != "" {
+		key = prefix + "." + key
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		for _, ga := range a.Value.Group() {
+			appendAttr(b, key, ga)
+		}
+		return
+	}
+	v := a.Value.String()
+	if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+		v = strconv.Quote(v)
+	}
+	b.WriteString(" " + key + "=" + v)
+}
+
+// discardHandler drops all records.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (d discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return d }
+func (d discardHandler) WithGroup(string) slog.Handler           { return d }
+
+// redactedFields are the request fields that may carry user content.
+var redactedFields = map[string]bool{
+	"prompt":  true,
+	"suffix":  true,
+	"system":  true,
+	"content": true,
+	"input":   true,
+	"images":  true,
+}
+
+// logBody formats a request body for logging only when the record is
+// actually written.
+type logBody struct {
+	body   interface{}
+	redact bool
+}
+
+func (b logBody) LogValue() slog.Value {
+	data, err := json.Marshal(b.body)
+	if err != nil {
+		return slog.StringValue(fmt.Sprintf("<%v>", err))
+	}
+	if !b.redact {
+		return slog.StringValue(string(data))
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return slog.StringValue(string(data))
+	}
+	data, _ = json.Marshal(redact(v))
+	return slog.StringValue(string(data))
+}
+
+// redact replaces user content in a decoded JSON value with its size.
+func redact(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, fv := range v {
+			if redactedFields[k] {
+				v[k] = redactedSize(fv)
+			} else {
+				v[k] = redact(fv)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redact(v[i])
+		}
+	}
+	return v
+}
+
+func redactedSize(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("[redacted %d bytes]", len(s))
+	}
+	data, _ := json.Marshal(v)
+	return fmt.Sprintf("[redacted %d bytes]", len(data))
+}
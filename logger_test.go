@@ -0,0 +1,129 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wiseinf/ollama-go/ollamatest"
+)
+
+// recordingLogger collects printf-style log lines by level.
+type recordingLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *recordingLogger) add(level, format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, level+" "+fmt.Sprintf(format, v...))
+}
+
+func (l *recordingLogger) Debug(format string, v ...interface{}) { l.add("DEBUG", format, v...) }
+func (l *recordingLogger) Info(format string, v ...interface{})  { l.add("INFO", format, v...) }
+func (l *recordingLogger) Error(format string, v ...interface{}) { l.add("ERROR", format, v...) }
+
+func TestLoggerHonorsDebug(t *testing.T) {
+	tests := []struct {
+		debug bool
+		want  int
+	}{
+		{false, 0},
+		{true, 2},
+	}
+	for _, tt := range tests {
+		logger := &recordingLogger{}
+		_, client := newTestServer(t, withClientOptions(WithLogger(logger), WithDebug(tt.debug)))
+		if _, err := client.ListModels(context.Background()); err != nil {
+			t.Fatalf("ListModels() error = %v", err)
+		}
+		if len(logger.lines) != tt.want {
+			t.Errorf("debug=%v: logged %q, want %d lines", tt.debug, logger.lines, tt.want)
+		}
+	}
+}
+
+func TestLoggerStructuredFields(t *testing.T) {
+	logger := &recordingLogger{}
+	server, client := newTestServer(t, withClientOptions(WithLogger(logger), WithDebug(true), WithRetryWaitTime(time.Millisecond)))
+	server.Inject("/api/generate", ollamatest.Fault{Status: http.StatusServiceUnavailable})
+
+	if _, err := client.Generate(context.Background(), &GenerateRequest{Model: "llama3.2:1b", Prompt: "Hi there"}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	got := strings.Join(logger.lines, "\n")
+	for _, want := range []string{
+		`DEBUG sending request method=POST endpoint=/api/generate model=llama3.2:1b attempt=1 headers.Content-Type=application/json body=`,
+		`DEBUG received response endpoint=/api/generate model=llama3.2:1b attempt=1 status=503 duration=`,
+		`DEBUG retrying request endpoint=/api/generate model=llama3.2:1b attempt=2 max_retries=3 wait=1ms`,
+		`status=200`,
+		`Hi there`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("log output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestSlogLoggerRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	_, client := newTestServer(t, withClientOptions(WithSlogLogger(logger), WithLogRedaction(true)))
+
+	_, err := client.Chat(context.Background(), &ChatRequest{
+		Model: "llama3.2:1b",
+		Messages: []ChatMessage{
+			{Role: UserRole, Content: "secret question", Images: []string{"aGVsbG8="}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+
+	var sent struct {
+		Msg      string `json:"msg"`
+		Endpoint string `json:"endpoint"`
+		Model    string `json:"model"`
+		Attempt  int    `json:"attempt"`
+		Body     string `json:"body"`
+	}
+	if err := json.Unmarshal(bytes.SplitN(buf.Bytes(), []byte("\n"), 2)[0], &sent); err != nil {
+		t.Fatalf("log line is not JSON: %v\n%s", err, buf.String())
+	}
+	if sent.Msg != "sending request" || sent.Endpoint != "/api/chat" || sent.Model != "llama3.2:1b" || sent.Attempt != 1 {
+		t.Errorf("sending request record = %+v", sent)
+	}
+	if strings.Contains(buf.String(), "secret") || strings.Contains(buf.String(), "aGVsbG8=") {
+		t.Errorf("log output contains user content:\n%s", buf.String())
+	}
+	for _, want := range []string{`"content":"[redacted 15 bytes]"`, `"images":"[redacted 12 bytes]"`, `"role":"user"`} {
+		if !strings.Contains(sent.Body, want) {
+			t.Errorf("body %s missing %s", sent.Body, want)
+		}
+	}
+}
+
+func TestLoggerHandlerAttrs(t *testing.T) {
+	logger := &recordingLogger{}
+	l := slog.New(&loggerHandler{logger: logger})
+	l.With("client", "a").WithGroup("req").Info("hello world", "path", "/api/x y", slog.Group("g", "n", 1))
+	l.Warn("careful")
+	l.Error("failed", "err", "")
+
+	want := []string{
+		`INFO hello world client=a req.path="/api/x y" req.g.n=1`,
+		`INFO careful`,
+		`ERROR failed err=""`,
+	}
+	if strings.Join(logger.lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines = %q, want %q", logger.lines, want)
+	}
+}
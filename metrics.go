@@ -0,0 +1,344 @@
+package ollama
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics receives instrumentation events from the client.
+type Metrics interface {
+	// ObserveRequest is called once per API call when it ends. status is
+	// the HTTP status of the last response, or 0 if none was received.
+	ObserveRequest(endpoint, model string, status int, err error, latency time.Duration)
+	// ObserveRetry is called before each retried attempt.
+	ObserveRetry(endpoint, model string)
+	// ObserveRateLimitWait is called with the time spent waiting for the
+	// client-side rate limiter.
+	ObserveRateLimitWait(wait time.Duration)
+	// ObserveTTFT is called with the time to the first chunk of a stream.
+	ObserveTTFT(endpoint, model string, ttft time.Duration)
+	// ObserveUsage is called with the token usage of a completed generation.
+	ObserveUsage(model string, usage Usage)
+}
+
+// noopMetrics discards all events.
+type noopMetrics struct{}
+
+func (noopMetrics) ObserveRequest(string, string, int, error, time.Duration) {}
+func (noopMetrics) ObserveRetry(string, string)                              {}
+func (noopMetrics) ObserveRateLimitWait(time.Duration)                       {}
+func (noopMetrics) ObserveTTFT(string, string, time.Duration)                {}
+func (noopMetrics) ObserveUsage(string, Usage)                               {}
+
+// observeCall reports a finished call to the client metrics.
+func (c *Client) observeCall(call *Call) {
+	model := call.Model()
+	c.metrics.ObserveRequest(call.Endpoint, model, call.StatusCode, call.Err, call.Duration)
+	if call.Stream && call.TTFT > 0 {
+		c.metrics.ObserveTTFT(call.Endpoint, model, call.TTFT)
+	}
+	if r, ok := call.Result.(interface{ Usage() Usage }); ok && call.Err == nil && !call.Cached {
+		c.metrics.ObserveUsage(model, r.Usage())
+	}
+}
+
+// Default histogram buckets.
+var (
+	latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+	waitBuckets    = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
+	rateBuckets    = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 5000}
+)
+
+// ExpvarMetrics is a Metrics implementation that keeps counters and
+// histograms in memory, publishes them through expvar and can render them
+// in the Prometheus text exposition format.
+type ExpvarMetrics struct {
+	mu         sync.Mutex
+	counters   map[string]*counterVec
+	histograms map[string]*histogramVec
+}
+
+// NewExpvarMetrics creates an ExpvarMetrics. If name is not empty the
+// metrics are published as an expvar variable under that name, which
+// panics if the name is already in use.
+func NewExpvarMetrics(name string) *ExpvarMetrics {
+	m := &ExpvarMetrics{
+		counters: map[string]*counterVec{
+			"ollama_requests_total":              newCounterVec("API calls made.", "endpoint", "model"),
+			"ollama_request_errors_total":        newCounterVec("API calls that failed, by HTTP status (0 when no response was received).", "endpoint", "model", "status"),
+			"ollama_retries_total":               newCounterVec("Retried attempts.", "endpoint", "model"),
+			"ollama_prompt_tokens_total":         newCounterVec("Prompt tokens evaluated.", "model"),
+			"ollama_eval_tokens_total":           newCounterVec("Tokens generated.", "model"),
+			"ollama_circuit_state_changes_total": newCounterVec("Circuit breaker state changes, by new state.", "host", "model", "state"),
+		},
+		histograms: map[string]*histogramVec{
+			"ollama_request_duration_seconds":    newHistogramVec("API call latency, including reading streams.", latencyBuckets, "endpoint", "model"),
+			"ollama_rate_limit_wait_seconds":     newHistogramVec("Time spent waiting for the client rate limiter.", waitBuckets),
+			"ollama_time_to_first_token_seconds": newHistogramVec("Time to the first chunk of a stream.", latencyBuckets, "endpoint", "model"),
+			"ollama_prompt_tokens_per_second":    newHistogramVec("Prompt evaluation rate reported by the server.", rateBuckets, "model"),
+			"ollama_eval_tokens_per_second":      newHistogramVec("Generation rate reported by the server.", rateBuckets, "model"),
+			"ollama_model_load_duration_seconds": newHistogramVec("Model load time reported by the server.", latencyBuckets, "model"),
+		},
+	}
+	if name != "" {
+		expvar.Publish(name, expvar.Func(m.snapshot))
+	}
+	return m
+}
+
+// ObserveRequest implements Metrics.
+func (m *ExpvarMetrics) ObserveRequest(endpoint, model string, status int, err error, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters["ollama_requests_total"].add(1, endpoint, model)
+	if err != nil || status >= 400 {
+		m.counters["ollama_request_errors_total"].add(1, endpoint, model, strconv.Itoa(status))
+	}
+	m.histograms["ollama_request_duration_seconds"].observe(latency.Seconds(), endpoint, model)
+}
+
+// ObserveRetry implements Metrics.
+func (m *ExpvarMetrics) ObserveRetry(endpoint, model string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters["ollama_retries_total"].add(1, endpoint, model)
+}
+
+// ObserveRateLimitWait implements Metrics.
+func (m *ExpvarMetrics) ObserveRateLimitWait(wait time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.histograms["ollama_rate_limit_wait_seconds"].observe(wait.Seconds())
+}
+
+// ObserveTTFT implements Metrics.
+func (m *ExpvarMetrics) ObserveTTFT(endpoint, model string, ttft time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.histograms["ollama_time_to_first_token_seconds"].observe(ttft.Seconds(), endpoint, model)
+}
+
+// ObserveCircuitState implements CircuitMetrics.
+func (m *ExpvarMetrics) ObserveCircuitState(host, model string, from, to CircuitState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters["ollama_circuit_state_changes_total"].add(1, host, model, to.String())
+}
+
+// ObserveUsage implements Metrics.
+func (m *ExpvarMetrics) ObserveUsage(model string, usage Usage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters["ollama_prompt_tokens_total"].add(float64(usage.PromptTokens), model)
+	m.counters["ollama_eval_tokens_total"].add(float64(usage.EvalTokens), model)
+	if usage.PromptEvalDuration > 0 {
+		m.histograms["ollama_prompt_tokens_per_second"].observe(usage.PromptTokensPerSecond(), model)
+	}
+	if usage.EvalDuration > 0 {
+		m.histograms["ollama_eval_tokens_per_second"].observe(usage.EvalTokensPerSecond(), model)
+	}
+	// Responses from an already loaded model report no load duration.
+	if usage.LoadDuration > 0 {
+		m.histograms["ollama_model_load_duration_seconds"].observe(usage.LoadDuration.Seconds(), model)
+	}
+}
+
+// Counter returns the current value of a counter, for tests and ad-hoc
+// inspection. Label values are given in the order the metric declares them.
+func (m *ExpvarMetrics) Counter(name string, labels ...string) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c, ok := m.counters[name]; ok {
+		return c.values[labelKey(labels)]
+	}
+	return 0
+}
+
+// HistogramCount returns the number of observations of a histogram.
+func (m *ExpvarMetrics) HistogramCount(name string, labels ...string) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if h, ok := m.histograms[name]; ok {
+		if s, ok := h.series[labelKey(labels)]; ok {
+			return s.count
+		}
+	}
+	return 0
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format.
+func (m *ExpvarMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WritePrometheus(w)
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format.
+func (m *ExpvarMetrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+	for _, name := range sortedKeys(m.counters) {
+		c := m.counters[name]
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", name, c.help, name)
+		for _, key := range sortedKeys(c.values) {
+			fmt.Fprintf(&b, "%s%s %s\n", name, formatLabels(c.labels, key, "", ""), formatFloat(c.values[key]))
+		}
+	}
+	for _, name := range sortedKeys(m.histograms) {
+		h := m.histograms[name]
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s histogram\n", name, h.help, name)
+		for _, key := range sortedKeys(h.series) {
+			s := h.series[key]
+			for i, upper := range h.buckets {
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", name, formatLabels(h.labels, key, "le", formatFloat(upper)), s.buckets[i])
+			}
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", name, formatLabels(h.labels, key, "le", "+Inf"), s.count)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", name, formatLabels(h.labels, key, "", ""), formatFloat(s.sum))
+			fmt.Fprintf(&b, "%s_count%s %d\n", name, formatLabels(h.labels, key, "", ""), s.count)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// snapshot returns the metrics as a JSON-friendly value for expvar.
+func (m *ExpvarMetrics) snapshot() interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make(map[string]interface{})
+	for name, c := range m.counters {
+		series := make(map[string]float64, len(c.values))
+		for key, v := range c.values {
+			series[strings.Join(splitKey(key), ",")] = v
+		}
+		out[name] = series
+	}
+	for name, h := range m.histograms {
+		series := make(map[string]interface{}, len(h.series))
+		for key, s := range h.series {
+			buckets := make(map[string]uint64, len(h.buckets))
+			for i, upper := range h.buckets {
+				buckets[formatFloat(upper)] = s.buckets[i]
+			}
+			series[strings.Join(splitKey(key), ",")] = map[string]interface{}{
+				"count":   s.count,
+				"sum":     s.sum,
+				"buckets": buckets,
+			}
+		}
+		out[name] = series
+	}
+	return out
+}
+
+// counterVec is a set of counters sharing label names.
+type counterVec struct {
+	help   string
+	labels []string
+	values map[string]float64
+}
+
+func newCounterVec(help string, labels ...string) *counterVec {
+	return &counterVec{help: help, labels: labels, values: make(map[string]float64)}
+}
+
+func (c *counterVec) add(v float64, labels ...string) {
+	c.values[labelKey(labels)] += v
+}
+
+// histogramVec is a set of cumulative histograms sharing buckets and label names.
+type histogramVec struct {
+	help    string
+	labels  []string
+	buckets []float64
+	series  map[string]*histogram
+}
+
+type histogram struct {
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+func newHistogramVec(help string, buckets []float64, labels ...string) *histogramVec {
+	return &histogramVec{help: help, labels: labels, buckets: buckets, series: make(map[string]*histogram)}
+}
+
+func (h *histogramVec) observe(v float64, labels ...string) {
+	if math.IsNaN(v) {
+		return
+	}
+	key := labelKey(labels)
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogram{buckets: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.buckets[i]++
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+// labelKey joins label values into a map key.
+func labelKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+func splitKey(key string) []string {
+	if key == "" {
+		return nil
+	}
+	return strings.Split(key, "\xff")
+}
+
+// labelEscaper escapes label values as the Prometheus text format expects:
+// only backslashes, double quotes and newlines.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels renders label pairs, plus an optional extra pair, in
+// Prometheus syntax.
+func formatLabels(names []string, key, extraName, extraValue string) string {
+	values := splitKey(key)
+	var pairs []string
+	for i, name := range names {
+		var v string
+		if i < len(values) {
+			v = values[i]
+		}
+		pairs = append(pairs, name+`="`+labelEscaper.Replace(v)+`"`)
+	}
+	if extraName != "" {
+		pairs = append(pairs, extraName+`="`+labelEscaper.Replace(extraValue)+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,116 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wiseinf/ollama-go/ollamatest"
+)
+
+func TestExpvarMetrics(t *testing.T) {
+	metrics := NewExpvarMetrics("ollama_test_metrics")
+	server, client := newTestServer(t, withClientOptions(WithRetryWaitTime(time.Millisecond), WithMetrics(metrics)))
+	ctx := context.Background()
+
+	server.Inject("/api/generate", ollamatest.Fault{Status: http.StatusServiceUnavailable})
+	if _, err := client.Generate(ctx, &GenerateRequest{Model: "llama3.2:1b", Prompt: "Hi"}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	stream, err := client.ChatStream(ctx, &ChatRequest{Model: "llama3.2:1b", Messages: []ChatMessage{{Role: UserRole, Content: "Hi"}}})
+	if err != nil {
+		t.Fatalf("ChatStream() error = %v", err)
+	}
+	for range stream {
+	}
+	server.Inject("/api/show", ollamatest.Fault{Status: http.StatusNotFound})
+	if _, err := client.ShowModel(ctx, "missing", nil); err == nil {
+		t.Fatal("ShowModel() error = nil, want not found")
+	}
+
+	counters := []struct {
+		name   string
+		labels []string
+		want   float64
+	}{
+		{"ollama_requests_total", []string{"/api/generate", "llama3.2:1b"}, 1},
+		{"ollama_requests_total", []string{"/api/chat", "llama3.2:1b"}, 1},
+		{"ollama_retries_total", []string{"/api/generate", "llama3.2:1b"}, 1},
+		{"ollama_request_errors_total", []string{"/api/show", "missing", "404"}, 1},
+		{"ollama_prompt_tokens_total", []string{"llama3.2:1b"}, 20},
+		{"ollama_eval_tokens_total", []string{"llama3.2:1b"}, 40},
+	}
+	for _, c := range counters {
+		if got := metrics.Counter(c.name, c.labels...); got != c.want {
+			t.Errorf("Counter(%s, %v) = %v, want %v", c.name, c.labels, got, c.want)
+		}
+	}
+	if got := metrics.HistogramCount("ollama_time_to_first_token_seconds", "/api/chat", "llama3.2:1b"); got != 1 {
+		t.Errorf("TTFT observations = %d, want 1", got)
+	}
+	if got := metrics.HistogramCount("ollama_rate_limit_wait_seconds"); got != 3 {
+		t.Errorf("rate limit wait observations = %d, want 3", got)
+	}
+	if got := metrics.HistogramCount("ollama_eval_tokens_per_second", "llama3.2:1b"); got != 2 {
+		t.Errorf("eval rate observations = %d, want 2", got)
+	}
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE ollama_requests_total counter\n",
+		`ollama_requests_total{endpoint="/api/generate",model="llama3.2:1b"} 1` + "\n",
+		`ollama_request_errors_total{endpoint="/api/show",model="missing",status="404"} 1` + "\n",
+		"# TYPE ollama_eval_tokens_per_second histogram\n",
+		`ollama_eval_tokens_per_second_bucket{model="llama3.2:1b",le="100"} 2` + "\n",
+		`ollama_eval_tokens_per_second_bucket{model="llama3.2:1b",le="+Inf"} 2` + "\n",
+		`ollama_eval_tokens_per_second_sum{model="llama3.2:1b"} 200` + "\n",
+		"ollama_rate_limit_wait_seconds_count 3\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Prometheus output missing %q:\n%s", want, body)
+		}
+	}
+
+	var published map[string]map[string]interface{}
+	if err := json.Unmarshal([]byte(expvar.Get("ollama_test_metrics").String()), &published); err != nil {
+		t.Fatalf("expvar value is not JSON: %v", err)
+	}
+	if got := published["ollama_requests_total"]["/api/chat,llama3.2:1b"]; got != float64(1) {
+		t.Errorf("expvar ollama_requests_total = %v, want 1", got)
+	}
+}
+
+func TestFormatLabels(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"llama3.2:1b", `{model="llama3.2:1b"}`},
+		{`say "hi"`, `{model="say \"hi\""}`},
+		{`C:\models`, `{model="C:\\models"}`},
+		{"a\nb", `{model="a\nb"}`},
+		{"tab\there", "{model=\"tab\there\"}"},
+		{"héllo", `{model="héllo"}`},
+	}
+	for _, tt := range tests {
+		if got := formatLabels([]string{"model"}, labelKey([]string{tt.value}), "", ""); got != tt.want {
+			t.Errorf("formatLabels(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestObserveUsageLoadDuration(t *testing.T) {
+	metrics := NewExpvarMetrics("")
+	metrics.ObserveUsage("llama3.2:1b", Usage{EvalTokens: 10})
+	metrics.ObserveUsage("llama3.2:1b", Usage{EvalTokens: 10, LoadDuration: 2 * time.Second})
+	if got := metrics.HistogramCount("ollama_model_load_duration_seconds", "llama3.2:1b"); got != 1 {
+		t.Errorf("load duration observations = %d, want only the response that loaded the model", got)
+	}
+}
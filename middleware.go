@@ -0,0 +1,179 @@
+package ollama
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Call describes a single API call as it passes through the middleware chain.
+type Call struct {
+	// Method and Endpoint identify the call, e.g. POST /api/chat.
+	Method   string
+	Endpoint string
+	// Request is the request struct passed to the client method, or nil.
+	Request interface{}
+	// Stream is set for calls whose response is an NDJSON stream.
+	Stream bool
+	// Header holds extra headers to send with the request.
+	Header http.Header
+	// Start is when the call entered the middleware chain.
+	Start time.Time
+	// Attempts is the number of HTTP requests sent, including retries.
+	Attempts int
+	// StatusCode is the HTTP status of the last response, or 0 if none was
+	// received.
+	StatusCode int
+	// TTFT is the time from Start until the first chunk of a stream arrived.
+	TTFT time.Duration
+	// Cached is set by a middleware that answered the call from a cache.
+	// Cached calls do not count towards token usage.
+	Cached bool
+
+	// Result is the decoded response once the call has ended. For streams it
+	// is the final chunk. It is nil for calls without a response body.
+	Result interface{}
+	// Err is the error the call ended with, including stream errors.
+	Err error
+	// Duration is the time from Start until the call ended. For streams this
+	// includes reading the whole stream.
+	Duration time.Duration
+
+	mu      sync.Mutex
+	onEnd   []func(*Call)
+	ended   bool
+	observe func(*Call)
+}
+
+// Handler performs an API call and returns the raw HTTP response.
+type Handler func(ctx context.Context, call *Call) (*http.Response, error)
+
+// Middleware wraps a Handler to observe or modify calls. A middleware may
+// inspect and change the Call before calling next, inspect the response or
+// error afterwards, or answer the call itself without calling next.
+type Middleware func(next Handler) Handler
+
+// WithMiddleware adds middlewares to the client. The first middleware is
+// the outermost and sees each call first.
+func WithMiddleware(middlewares ...Middleware) func(*ClientOptions) {
+	return func(o *ClientOptions) {
+		o.Middlewares = append(o.Middlewares, middlewares...)
+	}
+}
+
+// newCall creates a Call for a request to endpoint.
+func newCall(method, endpoint string, request interface{}, stream bool) *Call {
+	return &Call{
+		Method:   method,
+		Endpoint: endpoint,
+		Request:  request,
+		Stream:   stream,
+		Header:   make(http.Header),
+	}
+}
+
+// Model returns the model named by the call's request, or "" if it has none.
+func (c *Call) Model() string {
+	v := reflect.ValueOf(c.Request)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	for _, name := range []string{"Model", "Name"} {
+		if f := v.FieldByName(name); f.IsValid() && f.Kind() == reflect.String {
+			return f.String()
+		}
+	}
+	return ""
+}
+
+// subCall creates a call a middleware makes on behalf of c. It is observed
+// like the calls the client makes itself; the middleware must end it.
+func (c *Call) subCall(method, endpoint string, request interface{}) *Call {
+	sub := newCall(method, endpoint, request, false)
+	sub.Start = time.Now()
+	if c.observe != nil {
+		sub.observe = c.observe
+		sub.OnEnd(c.observe)
+	}
+	return sub
+}
+
+// OnEnd registers fn to run once the call has ended: when the response body
+// has been read and closed, or straight away if the call failed. For
+// streaming calls that is when the stream ends.
+func (c *Call) OnEnd(fn func(*Call)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onEnd = append(c.onEnd, fn)
+}
+
+// end records err, if any, and runs the OnEnd callbacks once.
+func (c *Call) end(err error) {
+	c.mu.Lock()
+	if c.ended {
+		c.mu.Unlock()
+		return
+	}
+	c.ended = true
+	if c.Err == nil {
+		c.Err = err
+	}
+	c.Duration = time.Since(c.Start)
+	callbacks := c.onEnd
+	c.mu.Unlock()
+
+	// Run innermost middleware callbacks first, mirroring how responses
+	// unwind through the chain.
+	for i := len(callbacks) - 1; i >= 0; i-- {
+		callbacks[i](c)
+	}
+}
+
+// do runs call through the middleware chain. The returned response body
+// ends the call when it is closed.
+func (c *Client) do(ctx context.Context, call *Call) (*http.Response, error) {
+	call.Start = time.Now()
+	call.observe = c.observeCall
+	call.OnEnd(c.observeCall)
+	ctx = c.startCallSpan(ctx, call)
+	resp, err := c.handler(ctx, call)
+	if err != nil {
+		call.end(err)
+		return nil, err
+	}
+	if call.Stream {
+		c.startStreamSpan(ctx, call)
+	}
+	resp.Body = &callBody{ReadCloser: resp.Body, call: call}
+	return resp, nil
+}
+
+// buildHandler composes the middlewares around the transport handler.
+func (c *Client) buildHandler() Handler {
+	h := c.sendRequest
+	for i := len(c.opts.Middlewares) - 1; i >= 0; i-- {
+		h = c.opts.Middlewares[i](h)
+	}
+	return h
+}
+
+// callBody ends its call when the response body is closed.
+type callBody struct {
+	io.ReadCloser
+	call *Call
+}
+
+func (b *callBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.call.end(nil)
+	return err
+}
@@ -0,0 +1,149 @@
+package ollama
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/wiseinf/ollama-go/ollamatest"
+)
+
+func TestMiddlewareSeesCalls(t *testing.T) {
+	var mu sync.Mutex
+	var ended []*Call
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, call *Call) (*http.Response, error) {
+				mu.Lock()
+				order = append(order, name)
+				mu.Unlock()
+				call.OnEnd(func(call *Call) {
+					mu.Lock()
+					defer mu.Unlock()
+					if name == "outer" {
+						ended = append(ended, call)
+					}
+				})
+				return next(ctx, call)
+			}
+		}
+	}
+	auth := func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (*http.Response, error) {
+			call.Header.Set("Authorization", "Bearer secret")
+			return next(ctx, call)
+		}
+	}
+
+	var gotAuth string
+	server, client := newTestServer(t, withHandler("/api/tags", func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		io.WriteString(w, `{"models": []}`)
+	}), withClientOptions(WithMiddleware(trace("outer"), trace("inner"), auth)))
+	req := &GenerateRequest{Model: "llama3.2:1b", Prompt: "Hi"}
+	if _, err := client.Generate(context.Background(), req); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if _, err := client.ListModels(context.Background()); err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+
+	if want := []string{"outer", "inner", "outer", "inner"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("middleware order = %v, want %v", order, want)
+	}
+	if gotAuth != "Bearer secret" {
+		t.Errorf("Authorization header = %q, want the middleware header", gotAuth)
+	}
+	if len(ended) != 2 {
+		t.Fatalf("ended calls = %d, want 2", len(ended))
+	}
+
+	call := ended[0]
+	if call.Method != "POST" || call.Endpoint != "/api/generate" || call.Request != req || call.Stream {
+		t.Errorf("call = %s %s stream=%v, want the generate call", call.Method, call.Endpoint, call.Stream)
+	}
+	if resp, ok := call.Result.(*GenerateResponse); !ok || resp.Response != server.Reply {
+		t.Errorf("call.Result = %#v, want the decoded response", call.Result)
+	}
+	if call.Err != nil || call.Duration <= 0 {
+		t.Errorf("call.Err = %v, Duration = %v", call.Err, call.Duration)
+	}
+}
+
+func TestMiddlewareStreamEnd(t *testing.T) {
+	var mu sync.Mutex
+	var ended *Call
+	server, client := newTestServer(t, withClientOptions(WithMaxRetries(0), WithMiddleware(func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (*http.Response, error) {
+			call.OnEnd(func(call *Call) {
+				mu.Lock()
+				defer mu.Unlock()
+				ended = call
+			})
+			return next(ctx, call)
+		}
+	})))
+
+	stream, err := client.ChatStream(context.Background(), &ChatRequest{Model: "llama3.2:1b", Messages: []ChatMessage{{Role: UserRole, Content: "Hi"}}})
+	if err != nil {
+		t.Fatalf("ChatStream() error = %v", err)
+	}
+	for range stream {
+	}
+	// The call ends before the channel is closed, so it is visible here.
+	if ended == nil {
+		t.Fatal("stream call did not end when the stream closed")
+	}
+	if resp, ok := ended.Result.(*ChatResponse); !ok || !resp.Done || !ended.Stream {
+		t.Errorf("call.Result = %#v, want the final chunk", ended.Result)
+	}
+
+	server.Inject("/api/generate", ollamatest.Fault{ErrorAt: 2, ErrorMessage: "boom"})
+	genStream, err := client.GenerateStream(context.Background(), &GenerateRequest{Model: "llama3.2:1b", Prompt: "Hi"})
+	if err != nil {
+		t.Fatalf("GenerateStream() error = %v", err)
+	}
+	for range genStream {
+	}
+	if ended.Err == nil || !strings.Contains(ended.Err.Error(), "boom") {
+		t.Errorf("call.Err = %v, want the stream error", ended.Err)
+	}
+
+	ended = nil
+	server.Inject("/api/generate", ollamatest.Fault{Status: http.StatusBadRequest})
+	if _, err := client.GenerateStream(context.Background(), &GenerateRequest{Model: "llama3.2:1b", Prompt: "Hi"}); err == nil {
+		t.Fatal("GenerateStream() error = nil, want the API error")
+	}
+	if ended == nil || ended.Err == nil {
+		t.Errorf("failed call did not end with its error: %+v", ended)
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	canned := func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     make(http.Header),
+				Body:       io.NopCloser(strings.NewReader(`{"embedding": [1, 2]}`)),
+			}, nil
+		}
+	}
+	server, client := newTestServer(t, withClientOptions(WithMiddleware(canned)))
+
+	resp, err := client.Embeddings(context.Background(), &EmbeddingRequest{Model: "all-minilm", Prompt: "Hi"})
+	if err != nil {
+		t.Fatalf("Embeddings() error = %v", err)
+	}
+	if !reflect.DeepEqual(resp.Embedding, []float32{1, 2}) {
+		t.Errorf("Embeddings() = %v, want the canned response", resp.Embedding)
+	}
+	if hits := server.Hits("/api/embeddings"); hits != 0 {
+		t.Errorf("server hits = %d, want 0", hits)
+	}
+}
@@ -0,0 +1,431 @@
+// Package ollamatest provides a fake Ollama server for testing clients.
+//
+// The server speaks enough of the Ollama REST API to exercise the client
+// end to end, and can be told to misbehave on demand: slow responses,
+// rate limiting, 5xx bursts, dropped connections and broken streams.
+package ollamatest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fault describes a misbehaviour the server applies to requests on a path.
+// Zero values disable the corresponding fault.
+type Fault struct {
+	// Times is the number of requests the fault applies to. 0 means once.
+	Times int
+	// Latency delays the response. The delay is cut short if the client
+	// gives up on the request.
+	Latency time.Duration
+	// Status responds with this HTTP status and an {"error": ...} body.
+	Status int
+	// RetryAfter sets the Retry-After header alongside Status. The header
+	// is in whole seconds, so RetryAfter is rounded up.
+	RetryAfter time.Duration
+	// Reset closes the connection without writing a response.
+	Reset bool
+	// TruncateAfter ends a stream after this many complete lines, writing
+	// only half of the next one.
+	TruncateAfter int
+	// MalformedAt replaces the n-th stream line (1-based) with invalid JSON.
+	MalformedAt int
+	// ErrorAt replaces the n-th stream line (1-based) with an error object
+	// and ends the stream.
+	ErrorAt int
+	// ErrorMessage is the message used by Status and ErrorAt.
+	ErrorMessage string
+	// Drip delays each stream line by this duration.
+	Drip time.Duration
+}
+
+// Server is a fake Ollama server backed by httptest.Server.
+type Server struct {
+	*httptest.Server
+
+	// Reply is the text returned by generate and chat. Streams send it one
+	// word per chunk.
+	Reply string
+	// Embedding is the vector returned by the embeddings endpoints.
+	Embedding []float32
+	// Models is the list returned by /api/tags and /api/ps.
+	Models []Model
+
+	mu       sync.Mutex
+	handlers map[string]http.HandlerFunc
+	faults   map[string][]Fault
+	hits     map[string]int
+	bodies   map[string][][]byte
+	blobs    map[string]int
+}
+
+// Model is a model entry served by the fake server.
+type Model struct {
+	Name   string `json:"name"`
+	Model  string `json:"model"`
+	Size   int64  `json:"size"`
+	Digest string `json:"digest"`
+}
+
+// NewServer starts a fake Ollama server. Callers should Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		Reply:     "Hello from the fake server!",
+		Embedding: []float32{0.1, 0.2, 0.3},
+		handlers:  make(map[string]http.HandlerFunc),
+		faults:    make(map[string][]Fault),
+		hits:      make(map[string]int),
+		bodies:    make(map[string][][]byte),
+		blobs:     make(map[string]int),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Inject queues a fault for requests to path. Faults are applied in the
+// order they were injected.
+func (s *Server) Inject(path string, f Fault) {
+	if f.Times <= 0 {
+		f.Times = 1
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[path] = append(s.faults[path], f)
+}
+
+// Handle overrides the built-in handler for path.
+func (s *Server) Handle(path string, h http.HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[path] = h
+}
+
+// Hits returns the number of requests received on path.
+func (s *Server) Hits(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits[path]
+}
+
+// Bodies returns the request bodies received on path, in order.
+func (s *Server) Bodies(path string) [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]byte(nil), s.bodies[path]...)
+}
+
+// Blobs returns the digests of the blobs pushed to the server and the
+// number of times each was uploaded.
+func (s *Server) Blobs() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	blobs := make(map[string]int, len(s.blobs))
+	for digest, n := range s.blobs {
+		blobs[digest] = n
+	}
+	return blobs
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	s.hits[r.URL.Path]++
+	s.bodies[r.URL.Path] = append(s.bodies[r.URL.Path], body)
+	fault := s.nextFault(r.URL.Path)
+	handler := s.handlers[r.URL.Path]
+	s.mu.Unlock()
+
+	if fault.Latency > 0 {
+		select {
+		case <-time.After(fault.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if fault.Reset {
+		resetConnection(w)
+		return
+	}
+
+	if fault.Status != 0 {
+		if fault.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(fault.RetryAfter.Seconds()))))
+		}
+		writeError(w, fault.Status, fault.message(http.StatusText(fault.Status)))
+		return
+	}
+
+	if handler != nil {
+		r.Body = io.NopCloser(strings.NewReader(string(body)))
+		handler(w, r)
+		return
+	}
+
+	if digest, ok := strings.CutPrefix(r.URL.Path, "/api/blobs/"); ok {
+		s.serveBlob(w, r, digest, body)
+		return
+	}
+
+	var req request
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	switch r.URL.Path {
+	case "/api/generate":
+		s.serveCompletion(w, r, fault, req, func(text string, done bool) interface{} {
+			return generateChunk{Model: req.Model, CreatedAt: time.Now().UTC(), Response: text, Done: done}
+		})
+	case "/api/chat":
+		s.serveCompletion(w, r, fault, req, func(text string, done bool) interface{} {
+			return chatChunk{Model: req.Model, CreatedAt: time.Now().UTC(), Message: message{Role: "assistant", Content: text}, Done: done}
+		})
+	case "/api/embeddings":
+		writeJSON(w, map[string]interface{}{"embedding": s.Embedding})
+	case "/api/embed":
+		writeJSON(w, map[string]interface{}{"model": req.Model, "embeddings": [][]float32{s.Embedding}})
+	case "/api/tags", "/api/ps":
+		writeJSON(w, map[string]interface{}{"models": s.models()})
+	case "/api/show":
+		s.serveShow(w, req)
+	case "/api/pull", "/api/push", "/api/create":
+		lines := []interface{}{
+			map[string]string{"status": "pulling manifest"},
+			map[string]string{"status": "writing manifest"},
+			map[string]string{"status": "success"},
+		}
+		if req.stream() {
+			writeStream(w, r, fault, lines)
+		} else {
+			writeJSON(w, lines[len(lines)-1])
+		}
+	case "/api/copy", "/api/delete":
+		if req.Model == "" && req.Source == "" {
+			writeError(w, http.StatusBadRequest, "model is required")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	case "/", "/api/version":
+		writeJSON(w, map[string]string{"version": "0.0.0-fake"})
+	default:
+		writeError(w, http.StatusNotFound, "404 page not found")
+	}
+}
+
+// serveBlob reports whether a blob exists or stores an upload, checking its
+// digest and size as Ollama does.
+func (s *Server) serveBlob(w http.ResponseWriter, r *http.Request, digest string, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodHead:
+		if _, ok := s.blobs[digest]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case http.MethodPost:
+		sum := sha256.Sum256(body)
+		if "sha256:"+hex.EncodeToString(sum[:]) != digest || r.ContentLength != int64(len(body)) {
+			writeError(w, http.StatusBadRequest, "digest mismatch")
+			return
+		}
+		s.blobs[digest]++
+		w.WriteHeader(http.StatusCreated)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// nextFault pops the next fault queued for path. s.mu must be held.
+func (s *Server) nextFault(path string) Fault {
+	queue := s.faults[path]
+	if len(queue) == 0 {
+		return Fault{}
+	}
+	f := queue[0]
+	queue[0].Times--
+	if queue[0].Times <= 0 {
+		s.faults[path] = queue[1:]
+	}
+	return f
+}
+
+func (s *Server) models() []Model {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	models := make([]Model, len(s.Models))
+	for i, m := range s.Models {
+		if m.Model == "" {
+			m.Model = m.Name
+		}
+		models[i] = m
+	}
+	return models
+}
+
+func (s *Server) serveShow(w http.ResponseWriter, req request) {
+	for _, m := range s.models() {
+		if m.Name == req.Model {
+			writeJSON(w, map[string]interface{}{
+				"modelfile": "FROM " + m.Name,
+				"details":   map[string]interface{}{"format": "gguf", "family": "llama"},
+				"model_info": map[string]interface{}{
+					"general.architecture": "llama",
+					"llama.context_length": 131072,
+				},
+				"capabilities": []string{"completion"},
+			})
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "model '"+req.Model+"' not found")
+}
+
+func (s *Server) serveCompletion(w http.ResponseWriter, r *http.Request, f Fault, req request, chunk func(text string, done bool) interface{}) {
+	if req.Model == "" {
+		writeError(w, http.StatusBadRequest, "model is required")
+		return
+	}
+	if !req.stream() {
+		writeJSON(w, withStats(chunk(s.Reply, true)))
+		return
+	}
+
+	words := strings.SplitAfter(s.Reply, " ")
+	lines := make([]interface{}, 0, len(words)+1)
+	for _, word := range words {
+		lines = append(lines, chunk(word, false))
+	}
+	lines = append(lines, withStats(chunk("", true)))
+	writeStream(w, r, f, lines)
+}
+
+// withStats decorates a final chunk with the timing fields Ollama reports.
+func withStats(v interface{}) interface{} {
+	b, _ := json.Marshal(v)
+	var m map[string]interface{}
+	json.Unmarshal(b, &m)
+	m["done_reason"] = "stop"
+	m["total_duration"] = int64(500 * time.Millisecond)
+	m["load_duration"] = int64(100 * time.Millisecond)
+	m["prompt_eval_count"] = 10
+	m["prompt_eval_duration"] = int64(100 * time.Millisecond)
+	m["eval_count"] = 20
+	m["eval_duration"] = int64(200 * time.Millisecond)
+	return m
+}
+
+func writeStream(w http.ResponseWriter, r *http.Request, f Fault, lines []interface{}) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	for i, line := range lines {
+		n := i + 1
+		if f.Drip > 0 {
+			select {
+			case <-time.After(f.Drip):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		b, _ := json.Marshal(line)
+		switch {
+		case n == f.ErrorAt:
+			b, _ = json.Marshal(map[string]string{"error": f.message("stream failed")})
+		case n == f.MalformedAt:
+			b = []byte(`{"response": "unterminated`)
+		case f.TruncateAfter > 0 && n > f.TruncateAfter:
+			w.Write(b[:len(b)/2])
+			return
+		}
+
+		w.Write(append(b, '\n'))
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if n == f.ErrorAt {
+			return
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// resetConnection drops the underlying TCP connection, sending an RST where
+// the platform allows it.
+func resetConnection(w http.ResponseWriter) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return
+	}
+	conn, _, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		tcp.SetLinger(0)
+	}
+	conn.Close()
+}
+
+func (f Fault) message(fallback string) string {
+	if f.ErrorMessage != "" {
+		return f.ErrorMessage
+	}
+	return fallback
+}
+
+// request holds the request fields the fake server looks at.
+type request struct {
+	Model  string `json:"model"`
+	Source string `json:"source"`
+	Stream *bool  `json:"stream"`
+}
+
+// stream reports whether the client asked for a streamed response.
+// Ollama streams unless told otherwise.
+func (r request) stream() bool {
+	return r.Stream == nil || *r.Stream
+}
+
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type generateChunk struct {
+	Model     string    `json:"model"`
+	CreatedAt time.Time `json:"created_at"`
+	Response  string    `json:"response"`
+	Done      bool      `json:"done"`
+}
+
+type chatChunk struct {
+	Model     string    `json:"model"`
+	CreatedAt time.Time `json:"created_at"`
+	Message   message   `json:"message"`
+	Done      bool      `json:"done"`
+}
@@ -1,27 +1,43 @@
 package ollama
 
 import (
+	"context"
+	"log/slog"
+	"net"
 	"net/http"
 	"time"
 )
 
 // ClientOptions includes options for client configuration
 type ClientOptions struct {
-	BaseURL          string
-	HTTPClient       *http.Client
-	MaxRetries       int
-	RetryWaitTime    time.Duration
-	RetryMaxWaitTime time.Duration
-	RateLimit        int // 每秒请求数
-	Timeout          time.Duration
-	Debug            bool
-	Logger           Logger
+	BaseURL           string
+	HTTPClient        *http.Client
+	Dialer            func(ctx context.Context, network, addr string) (net.Conn, error)
+	MaxRetries        int
+	RetryWaitTime     time.Duration
+	RetryMaxWaitTime  time.Duration
+	RateLimit         int // 每秒请求数
+	Timeout           time.Duration
+	Debug             bool
+	Logger            Logger
+	SlogLogger        *slog.Logger
+	RedactLogs        bool
+	Headers           http.Header
+	BearerToken       string
+	BasicAuthUsername string
+	BasicAuthPassword string
+	TokenSource       TokenSource
+	CircuitBreaker    *CircuitBreaker
+	UsageTracker      *UsageTracker
+	Middlewares       []Middleware
+	Metrics           Metrics
+	Tracer            Tracer
 }
 
 // default options
 func defaultOptions() *ClientOptions {
 	return &ClientOptions{
-		BaseURL:          "http://localhost:11434",
+		BaseURL:          DefaultHost,
 		MaxRetries:       3,
 		RetryWaitTime:    time.Second,
 		RetryMaxWaitTime: time.Second * 30,
@@ -56,6 +72,18 @@ func WithRetryWaitTime(duration time.Duration) func(*ClientOptions) {
 	}
 }
 
+func WithRetryMaxWaitTime(duration time.Duration) func(*ClientOptions) {
+	return func(o *ClientOptions) {
+		o.RetryMaxWaitTime = duration
+	}
+}
+
+func WithTimeout(timeout time.Duration) func(*ClientOptions) {
+	return func(o *ClientOptions) {
+		o.Timeout = timeout
+	}
+}
+
 func WithRateLimit(rps int) func(*ClientOptions) {
 	return func(o *ClientOptions) {
 		o.RateLimit = rps
@@ -73,3 +101,17 @@ func WithLogger(logger Logger) func(*ClientOptions) {
 		o.Logger = logger
 	}
 }
+
+// WithUsageTracker records usage into tracker, which may be shared between clients
+func WithUsageTracker(tracker *UsageTracker) func(*ClientOptions) {
+	return func(o *ClientOptions) {
+		o.UsageTracker = tracker
+	}
+}
+
+// WithMetrics records request metrics into m
+func WithMetrics(m Metrics) func(*ClientOptions) {
+	return func(o *ClientOptions) {
+		o.Metrics = m
+	}
+}
@@ -0,0 +1,554 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// API is the set of Ollama API calls. Client and Pool both implement it.
+type API interface {
+	Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error)
+	GenerateStream(ctx context.Context, req *GenerateRequest) (<-chan GenerateStreamResponse, error)
+	Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error)
+	ChatStream(ctx context.Context, req *ChatRequest) (<-chan ChatStreamResponse, error)
+	Embeddings(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error)
+	ListModels(ctx context.Context) ([]ListedModel, error)
+	ListRunningModels(ctx context.Context) ([]RunningModel, error)
+	ShowModel(ctx context.Context, name string, opts *ShowModelOptions) (*ShowModelResponse, error)
+	CreateModel(ctx context.Context, req *CreateModelRequest) error
+	CreateModelStream(ctx context.Context, req *CreateModelRequest) (<-chan ModelResponse, error)
+	CopyModel(ctx context.Context, req *CopyModelRequest) error
+	DeleteModel(ctx context.Context, name string) error
+	LoadModel(ctx context.Context, name string, keepAlive time.Duration) error
+	UnloadModel(ctx context.Context, name string) error
+	PullModel(ctx context.Context, req *PullModelRequest) (<-chan ModelResponse, error)
+	PushModel(ctx context.Context, req *PushModelRequest) (<-chan ModelResponse, error)
+}
+
+var (
+	_ API = (*Client)(nil)
+	_ API = (*Pool)(nil)
+)
+
+// Strategy selects the order in which a Pool tries its endpoints.
+type Strategy int
+
+const (
+	// RoundRobin rotates through the endpoints.
+	RoundRobin Strategy = iota
+	// LeastOutstanding prefers the endpoint with the fewest requests in
+	// flight.
+	LeastOutstanding
+	// ModelAffinity prefers endpoints that have the requested model loaded,
+	// then the fewest requests in flight.
+	ModelAffinity
+)
+
+// String returns the name of the strategy.
+func (s Strategy) String() string {
+	switch s {
+	case RoundRobin:
+		return "round_robin"
+	case LeastOutstanding:
+		return "least_outstanding"
+	case ModelAffinity:
+		return "model_affinity"
+	}
+	return fmt.Sprintf("Strategy(%d)", int(s))
+}
+
+// PoolOptions configures a Pool
+type PoolOptions struct {
+	Strategy            Strategy
+	HealthCheckInterval time.Duration
+	HealthCheckTimeout  time.Duration
+	ClientOptions       []ClientOption
+	HedgeDelay          time.Duration
+	HedgePercentile     float64
+}
+
+// PoolOption is a function that modifies the pool options
+type PoolOption func(*PoolOptions)
+
+// WithStrategy sets how the pool picks an endpoint
+func WithStrategy(s Strategy) PoolOption {
+	return func(o *PoolOptions) {
+		o.Strategy = s
+	}
+}
+
+// WithHealthCheckInterval sets how often endpoints are health checked. Zero
+// disables background health checks.
+func WithHealthCheckInterval(d time.Duration) PoolOption {
+	return func(o *PoolOptions) {
+		o.HealthCheckInterval = d
+	}
+}
+
+// WithHealthCheckTimeout bounds each health check
+func WithHealthCheckTimeout(d time.Duration) PoolOption {
+	return func(o *PoolOptions) {
+		o.HealthCheckTimeout = d
+	}
+}
+
+// WithClientOptions sets the options of the per-endpoint clients. The base
+// URL is set by the pool.
+func WithClientOptions(opts ...ClientOption) PoolOption {
+	return func(o *PoolOptions) {
+		o.ClientOptions = append(o.ClientOptions, opts...)
+	}
+}
+
+// Pool spreads calls across several Ollama servers. It health checks the
+// servers in the background, routes calls according to its Strategy and
+// fails over to the next server on connection errors or when a server's
+// circuit breaker is open.
+//
+// Every call goes to a single server, including model management calls
+// such as PullModel. Use Client to address a specific server.
+type Pool struct {
+	endpoints []*poolEndpoint
+	opts      *PoolOptions
+	usage     *UsageTracker
+	next      atomic.Uint64
+	hedger    hedger
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+// poolEndpoint is a server in a pool.
+type poolEndpoint struct {
+	url         string
+	client      *Client
+	outstanding atomic.Int64
+	healthy     atomic.Bool
+
+	mu     sync.Mutex
+	models map[string]bool
+	// answered are the models answered since the last health check, kept
+	// when the check replaces models.
+	answered map[string]bool
+}
+
+// EndpointStatus describes the state of a pool endpoint.
+type EndpointStatus struct {
+	URL         string
+	Healthy     bool
+	Outstanding int
+	// Models are the models last seen loaded on the server.
+	Models []string
+}
+
+// NewPool creates a pool of clients for baseURLs. Clients are created
+// without retries so that the pool fails over quickly; pass WithMaxRetries
+// through WithClientOptions to change that. Call Close to stop the health
+// checks.
+func NewPool(baseURLs []string, options ...PoolOption) (*Pool, error) {
+	if len(baseURLs) == 0 {
+		return nil, errors.New("pool requires at least one base URL")
+	}
+	opts := &PoolOptions{
+		Strategy:            RoundRobin,
+		HealthCheckInterval: 30 * time.Second,
+		HealthCheckTimeout:  5 * time.Second,
+	}
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	p := &Pool{opts: opts, usage: NewUsageTracker(), stop: make(chan struct{})}
+	p.hedger.latencies = make(map[string]*latencyWindow)
+	for _, u := range baseURLs {
+		clientOpts := append([]ClientOption{WithMaxRetries(0), WithUsageTracker(p.usage)}, opts.ClientOptions...)
+		clientOpts = append(clientOpts, WithBaseURL(u))
+		ep := &poolEndpoint{url: u, client: NewClient(clientOpts...), models: make(map[string]bool), answered: make(map[string]bool)}
+		ep.healthy.Store(true)
+		p.endpoints = append(p.endpoints, ep)
+	}
+
+	if opts.HealthCheckInterval > 0 {
+		p.wg.Add(1)
+		go p.healthLoop()
+	}
+	return p, nil
+}
+
+// Close stops the background health checks.
+func (p *Pool) Close() error {
+	p.once.Do(func() { close(p.stop) })
+	p.wg.Wait()
+	return nil
+}
+
+// Clients returns the per-endpoint clients in the order given to NewPool.
+func (p *Pool) Clients() []*Client {
+	clients := make([]*Client, len(p.endpoints))
+	for i, ep := range p.endpoints {
+		clients[i] = ep.client
+	}
+	return clients
+}
+
+// Usage returns the usage recorded across all endpoints.
+func (p *Pool) Usage() *UsageTracker {
+	return p.usage
+}
+
+// Endpoints returns the current state of each endpoint.
+func (p *Pool) Endpoints() []EndpointStatus {
+	statuses := make([]EndpointStatus, len(p.endpoints))
+	for i, ep := range p.endpoints {
+		ep.mu.Lock()
+		models := make([]string, 0, len(ep.models))
+		for m := range ep.models {
+			models = append(models, m)
+		}
+		ep.mu.Unlock()
+		sort.Strings(models)
+		statuses[i] = EndpointStatus{
+			URL:         ep.url,
+			Healthy:     ep.healthy.Load(),
+			Outstanding: int(ep.outstanding.Load()),
+			Models:      models,
+		}
+	}
+	return statuses
+}
+
+func (p *Pool) healthLoop() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(p.opts.HealthCheckInterval)
+	defer ticker.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-p.stop
+		cancel()
+	}()
+
+	for {
+		p.CheckHealth(ctx)
+		select {
+		case <-ticker.C:
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// CheckHealth checks every endpoint now by listing its running models,
+// which also refreshes the data used by ModelAffinity.
+func (p *Pool) CheckHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, ep := range p.endpoints {
+		wg.Add(1)
+		go func(ep *poolEndpoint) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, p.opts.HealthCheckTimeout)
+			defer cancel()
+			models, err := ep.client.ListRunningModels(ctx)
+			if err != nil {
+				if ctx.Err() != nil && !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					return
+				}
+				p.setHealthy(ep, false, err)
+				return
+			}
+			loaded := make(map[string]bool, len(models))
+			for _, m := range models {
+				loaded[normalizeModelName(m.Name)] = true
+			}
+			ep.mu.Lock()
+			for m := range ep.answered {
+				loaded[m] = true
+			}
+			ep.models = loaded
+			ep.answered = make(map[string]bool)
+			ep.mu.Unlock()
+			p.setHealthy(ep, true, nil)
+		}(ep)
+	}
+	wg.Wait()
+}
+
+// setHealthy records the health of ep, logging changes.
+func (p *Pool) setHealthy(ep *poolEndpoint, healthy bool, err error) {
+	if ep.healthy.Swap(healthy) == healthy {
+		return
+	}
+	if healthy {
+		ep.client.logger.LogAttrs(context.Background(), slog.LevelInfo, "pool endpoint healthy", slog.String("endpoint", ep.url))
+		return
+	}
+	ep.client.logger.LogAttrs(context.Background(), slog.LevelError, "pool endpoint unhealthy",
+		slog.String("endpoint", ep.url),
+		slog.Any("error", err),
+	)
+}
+
+// order returns the endpoints to try for a call to model, best first.
+// Unhealthy endpoints come last so they are still tried if nothing else
+// works.
+func (p *Pool) order(model string) []*poolEndpoint {
+	n := len(p.endpoints)
+	start := int(p.next.Add(1)-1) % n
+	eps := make([]*poolEndpoint, n)
+	for i := range eps {
+		eps[i] = p.endpoints[(start+i)%n]
+	}
+
+	model = normalizeModelName(model)
+	loaded := func(ep *poolEndpoint) bool {
+		if p.opts.Strategy != ModelAffinity || model == "" {
+			return false
+		}
+		ep.mu.Lock()
+		defer ep.mu.Unlock()
+		return ep.models[model]
+	}
+	rank := make(map[*poolEndpoint][3]int64, n)
+	for _, ep := range eps {
+		var r [3]int64
+		if !ep.healthy.Load() {
+			r[0] = 1
+		}
+		if !loaded(ep) {
+			r[1] = 1
+		}
+		if p.opts.Strategy != RoundRobin {
+			r[2] = ep.outstanding.Load()
+		}
+		rank[ep] = r
+	}
+	sort.SliceStable(eps, func(i, j int) bool {
+		a, b := rank[eps[i]], rank[eps[j]]
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return false
+	})
+	return eps
+}
+
+// isConnError reports whether err means the server could not be reached.
+// Only failures to connect count: once a request may have been sent, as on a
+// timeout or a dropped connection, it is not retried on another endpoint.
+func isConnError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var oerr *net.OpError
+	if errors.As(err, &oerr) && oerr.Op == "dial" {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// failover reports whether a call that failed on ep with err should move on
+// to the next endpoint, marking ep unhealthy if it could not be reached.
+func (p *Pool) failover(ctx context.Context, ep *poolEndpoint, err error) bool {
+	if errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
+	if isConnError(ctx, err) {
+		p.setHealthy(ep, false, err)
+		return true
+	}
+	return false
+}
+
+// succeeded records that ep answered a call for model.
+func (p *Pool) succeeded(ep *poolEndpoint, model string) {
+	p.setHealthy(ep, true, nil)
+	if model != "" {
+		model = normalizeModelName(model)
+		ep.mu.Lock()
+		ep.models[model] = true
+		ep.answered[model] = true
+		ep.mu.Unlock()
+	}
+}
+
+// poolCall runs fn against the endpoints in order until one is reachable.
+// release is called once the endpoint is no longer in use; for streams
+// that is when the stream ends.
+func poolCall[T any](ctx context.Context, p *Pool, model string, fn func(*Client) (T, error), stream func(T, func()) T) (T, error) {
+	var zero T
+	var err error
+	for _, ep := range p.order(model) {
+		ep.outstanding.Add(1)
+		release := func() { ep.outstanding.Add(-1) }
+
+		var result T
+		result, err = fn(ep.client)
+		if err != nil {
+			release()
+			if p.failover(ctx, ep, err) {
+				continue
+			}
+			return zero, err
+		}
+
+		p.succeeded(ep, model)
+		if stream != nil {
+			return stream(result, release), nil
+		}
+		release()
+		return result, nil
+	}
+	return zero, fmt.Errorf("all pool endpoints failed: %w", err)
+}
+
+// releaseOnClose forwards in and calls release once in is closed.
+func releaseOnClose[T any](in <-chan T, release func()) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		defer release()
+		for v := range in {
+			out <- v
+		}
+	}()
+	return out
+}
+
+// Generate sends a generate request to an endpoint of the pool
+func (p *Pool) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	return hedgedCall(ctx, p, "/api/generate", req.Model, func(ctx context.Context, c *Client) (*GenerateResponse, error) {
+		r := *req
+		return c.Generate(ctx, &r)
+	})
+}
+
+// GenerateStream sends a streaming generate request to an endpoint of the pool
+func (p *Pool) GenerateStream(ctx context.Context, req *GenerateRequest) (<-chan GenerateStreamResponse, error) {
+	return poolCall(ctx, p, req.Model, func(c *Client) (<-chan GenerateStreamResponse, error) {
+		return c.GenerateStream(ctx, req)
+	}, releaseOnClose[GenerateStreamResponse])
+}
+
+// Chat sends a chat request to an endpoint of the pool
+func (p *Pool) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	return hedgedCall(ctx, p, "/api/chat", req.Model, func(ctx context.Context, c *Client) (*ChatResponse, error) {
+		r := *req
+		return c.Chat(ctx, &r)
+	})
+}
+
+// ChatStream sends a streaming chat request to an endpoint of the pool
+func (p *Pool) ChatStream(ctx context.Context, req *ChatRequest) (<-chan ChatStreamResponse, error) {
+	return poolCall(ctx, p, req.Model, func(c *Client) (<-chan ChatStreamResponse, error) {
+		return c.ChatStream(ctx, req)
+	}, releaseOnClose[ChatStreamResponse])
+}
+
+// Embeddings sends an embeddings request to an endpoint of the pool
+func (p *Pool) Embeddings(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	return hedgedCall(ctx, p, "/api/embeddings", req.Model, func(ctx context.Context, c *Client) (*EmbeddingResponse, error) {
+		r := *req
+		return c.Embeddings(ctx, &r)
+	})
+}
+
+// ListModels lists the models of an endpoint of the pool
+func (p *Pool) ListModels(ctx context.Context) ([]ListedModel, error) {
+	return poolCall(ctx, p, "", func(c *Client) ([]ListedModel, error) {
+		return c.ListModels(ctx)
+	}, nil)
+}
+
+// ListRunningModels lists the running models of an endpoint of the pool
+func (p *Pool) ListRunningModels(ctx context.Context) ([]RunningModel, error) {
+	return poolCall(ctx, p, "", func(c *Client) ([]RunningModel, error) {
+		return c.ListRunningModels(ctx)
+	}, nil)
+}
+
+// ShowModel shows a model on an endpoint of the pool
+func (p *Pool) ShowModel(ctx context.Context, name string, opts *ShowModelOptions) (*ShowModelResponse, error) {
+	return poolCall(ctx, p, "", func(c *Client) (*ShowModelResponse, error) {
+		return c.ShowModel(ctx, name, opts)
+	}, nil)
+}
+
+// CreateModel creates a model on an endpoint of the pool
+func (p *Pool) CreateModel(ctx context.Context, req *CreateModelRequest) error {
+	_, err := poolCall(ctx, p, "", func(c *Client) (struct{}, error) {
+		return struct{}{}, c.CreateModel(ctx, req)
+	}, nil)
+	return err
+}
+
+// CreateModelStream creates a model on an endpoint of the pool, streaming
+// its progress
+func (p *Pool) CreateModelStream(ctx context.Context, req *CreateModelRequest) (<-chan ModelResponse, error) {
+	return poolCall(ctx, p, "", func(c *Client) (<-chan ModelResponse, error) {
+		return c.CreateModelStream(ctx, req)
+	}, releaseOnClose[ModelResponse])
+}
+
+// CopyModel copies a model on an endpoint of the pool
+func (p *Pool) CopyModel(ctx context.Context, req *CopyModelRequest) error {
+	_, err := poolCall(ctx, p, "", func(c *Client) (struct{}, error) {
+		return struct{}{}, c.CopyModel(ctx, req)
+	}, nil)
+	return err
+}
+
+// DeleteModel deletes a model on an endpoint of the pool
+func (p *Pool) DeleteModel(ctx context.Context, name string) error {
+	_, err := poolCall(ctx, p, "", func(c *Client) (struct{}, error) {
+		return struct{}{}, c.DeleteModel(ctx, name)
+	}, nil)
+	return err
+}
+
+// LoadModel loads a model on an endpoint of the pool
+func (p *Pool) LoadModel(ctx context.Context, name string, keepAlive time.Duration) error {
+	_, err := poolCall(ctx, p, name, func(c *Client) (struct{}, error) {
+		return struct{}{}, c.LoadModel(ctx, name, keepAlive)
+	}, nil)
+	return err
+}
+
+// UnloadModel unloads a model from every healthy endpoint of the pool
+func (p *Pool) UnloadModel(ctx context.Context, name string) error {
+	var errs []error
+	for _, ep := range p.endpoints {
+		if !ep.healthy.Load() {
+			continue
+		}
+		if err := ep.client.UnloadModel(ctx, name); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ep.url, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PullModel pulls a model on an endpoint of the pool
+func (p *Pool) PullModel(ctx context.Context, req *PullModelRequest) (<-chan ModelResponse, error) {
+	return poolCall(ctx, p, "", func(c *Client) (<-chan ModelResponse, error) {
+		return c.PullModel(ctx, req)
+	}, releaseOnClose[ModelResponse])
+}
+
+// PushModel pushes a model from an endpoint of the pool
+func (p *Pool) PushModel(ctx context.Context, req *PushModelRequest) (<-chan ModelResponse, error) {
+	return poolCall(ctx, p, "", func(c *Client) (<-chan ModelResponse, error) {
+		return c.PushModel(ctx, req)
+	}, releaseOnClose[ModelResponse])
+}
@@ -0,0 +1,191 @@
+package ollama
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/wiseinf/ollama-go/ollamatest"
+)
+
+// newTestPool starts n fake servers with newTestServer and a pool over
+// them, without background health checks unless opts set an interval.
+func newTestPool(t *testing.T, n int, opts ...PoolOption) (*Pool, []*ollamatest.Server) {
+	t.Helper()
+	var servers []*ollamatest.Server
+	var urls []string
+	for i := 0; i < n; i++ {
+		s, _ := newTestServer(t)
+		servers = append(servers, s)
+		urls = append(urls, s.URL)
+	}
+	opts = append([]PoolOption{WithHealthCheckInterval(0), WithClientOptions(WithLogger(&recordingLogger{}))}, opts...)
+	pool, err := NewPool(urls, opts...)
+	if err != nil {
+		t.Fatalf("NewPool() error = %v", err)
+	}
+	t.Cleanup(func() { pool.Close() })
+	return pool, servers
+}
+
+func generateN(t *testing.T, api API, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if _, err := api.Generate(context.Background(), &GenerateRequest{Model: "llama3.2:1b", Prompt: "Hi"}); err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+	}
+}
+
+func TestPoolRoundRobin(t *testing.T) {
+	pool, servers := newTestPool(t, 3)
+	generateN(t, pool, 6)
+	for i, s := range servers {
+		if hits := s.Hits("/api/generate"); hits != 2 {
+			t.Errorf("server %d hits = %d, want 2", i, hits)
+		}
+	}
+	if got := pool.Usage().Total().Requests; got != 6 {
+		t.Errorf("pool usage requests = %d, want 6", got)
+	}
+}
+
+func TestPoolFailover(t *testing.T) {
+	pool, servers := newTestPool(t, 2)
+	servers[0].Close()
+
+	generateN(t, pool, 4)
+	if hits := servers[1].Hits("/api/generate"); hits != 4 {
+		t.Errorf("healthy server hits = %d, want 4", hits)
+	}
+	status := pool.Endpoints()
+	if status[0].Healthy || !status[1].Healthy {
+		t.Errorf("endpoints = %+v, want the closed server unhealthy", status)
+	}
+
+	servers[1].Close()
+	if _, err := pool.Generate(context.Background(), &GenerateRequest{Model: "llama3.2:1b", Prompt: "Hi"}); err == nil {
+		t.Error("Generate() error = nil with every server down")
+	}
+}
+
+func TestPoolSlowEndpointNoFailover(t *testing.T) {
+	pool, servers := newTestPool(t, 2, WithClientOptions(WithTimeout(50*time.Millisecond), WithMaxRetries(0)))
+	servers[0].Inject("/api/generate", ollamatest.Fault{Latency: 200 * time.Millisecond})
+
+	if _, err := pool.Generate(context.Background(), &GenerateRequest{Model: "llama3.2:1b", Prompt: "Hi"}); err == nil {
+		t.Fatal("Generate() error = nil, want the timeout")
+	}
+	if hits := servers[1].Hits("/api/generate"); hits != 0 {
+		t.Errorf("second server hits = %d, want no failover after a timeout", hits)
+	}
+	if status := pool.Endpoints(); !status[0].Healthy {
+		t.Errorf("endpoints = %+v, want the slow server still healthy", status)
+	}
+}
+
+func TestPoolLeastOutstanding(t *testing.T) {
+	pool, servers := newTestPool(t, 2, WithStrategy(LeastOutstanding))
+
+	stream, err := pool.ChatStream(context.Background(), &ChatRequest{Model: "llama3.2:1b", Messages: []ChatMessage{{Role: UserRole, Content: "Hi"}}})
+	if err != nil {
+		t.Fatalf("ChatStream() error = %v", err)
+	}
+	busy := 0
+	if servers[1].Hits("/api/chat") == 1 {
+		busy = 1
+	}
+
+	generateN(t, pool, 3)
+	if hits := servers[1-busy].Hits("/api/generate"); hits != 3 {
+		t.Errorf("idle server hits = %d, want 3 while the other serves a stream", hits)
+	}
+
+	for range stream {
+	}
+	deadline := time.Now().Add(time.Second)
+	for pool.Endpoints()[busy].Outstanding != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := pool.Endpoints()[busy].Outstanding; n != 0 {
+		t.Errorf("outstanding after stream end = %d, want 0", n)
+	}
+}
+
+func TestPoolModelAffinity(t *testing.T) {
+	pool, servers := newTestPool(t, 3, WithStrategy(ModelAffinity))
+	servers[2].Models = []ollamatest.Model{{Name: "llama3.2:1b"}}
+	pool.CheckHealth(context.Background())
+
+	generateN(t, pool, 3)
+	if hits := servers[2].Hits("/api/generate"); hits != 3 {
+		t.Errorf("server with the model loaded hits = %d, want 3", hits)
+	}
+	if got := pool.Endpoints()[2].Models; len(got) != 1 || got[0] != "llama3.2:1b" {
+		t.Errorf("endpoint models = %v", got)
+	}
+}
+
+func TestPoolModelAffinityUntagged(t *testing.T) {
+	pool, servers := newTestPool(t, 3, WithStrategy(ModelAffinity))
+	servers[1].Models = []ollamatest.Model{{Name: "llama3:latest"}}
+	pool.CheckHealth(context.Background())
+
+	for i := 0; i < 3; i++ {
+		if _, err := pool.Generate(context.Background(), &GenerateRequest{Model: "llama3", Prompt: "Hi"}); err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		// Health checks agree with the names recorded for answered calls.
+		pool.CheckHealth(context.Background())
+	}
+	if hits := servers[1].Hits("/api/generate"); hits != 3 {
+		t.Errorf("server with llama3:latest loaded hits = %d, want 3", hits)
+	}
+	if got := pool.Endpoints()[1].Models; len(got) != 1 || got[0] != "llama3:latest" {
+		t.Errorf("endpoint models = %v, want [llama3:latest]", got)
+	}
+
+	// A model answered since the last check is kept even if /api/ps
+	// raced with the call.
+	pool.succeeded(pool.endpoints[0], "mistral")
+	pool.CheckHealth(context.Background())
+	if got := pool.Endpoints()[0].Models; len(got) != 1 || got[0] != "mistral:latest" {
+		t.Errorf("endpoint models = %v, want [mistral:latest]", got)
+	}
+}
+
+func TestNormalizeModelName(t *testing.T) {
+	tests := map[string]string{
+		"":                              "",
+		"llama3":                        "llama3:latest",
+		"llama3.2:1b":                   "llama3.2:1b",
+		"library/llama3":                "library/llama3:latest",
+		"localhost:5000/llama3":         "localhost:5000/llama3:latest",
+		"localhost:5000/llama3:8b-q4_0": "localhost:5000/llama3:8b-q4_0",
+	}
+	for name, want := range tests {
+		if got := normalizeModelName(name); got != want {
+			t.Errorf("normalizeModelName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestPoolHealthCheck(t *testing.T) {
+	pool, servers := newTestPool(t, 2, WithHealthCheckInterval(10*time.Millisecond))
+	servers[0].Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for pool.Endpoints()[0].Healthy && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if status := pool.Endpoints(); status[0].Healthy || !status[1].Healthy {
+		t.Errorf("endpoints = %+v, want the closed server marked unhealthy", status)
+	}
+	if servers[1].Hits("/api/ps") == 0 {
+		t.Error("healthy server was never checked")
+	}
+
+	if _, err := NewPool(nil); err == nil {
+		t.Error("NewPool(nil) error = nil")
+	}
+}
@@ -0,0 +1,270 @@
+package ollama
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SemanticCacheOptions configures a SemanticCache
+type SemanticCacheOptions struct {
+	// Threshold is the cosine similarity a cached prompt needs to answer a
+	// new one, from -1 to 1.
+	Threshold float64
+	// MaxEntries bounds the number of cached answers; the oldest are
+	// evicted first.
+	MaxEntries int
+	// TTL is how long answers are kept. Zero keeps them until evicted.
+	TTL time.Duration
+}
+
+// SemanticCacheOption is a function that modifies the semantic cache options
+type SemanticCacheOption func(*SemanticCacheOptions)
+
+// WithSimilarityThreshold sets the similarity a cached prompt needs to match
+func WithSimilarityThreshold(threshold float64) SemanticCacheOption {
+	return func(o *SemanticCacheOptions) {
+		o.Threshold = threshold
+	}
+}
+
+// WithMaxEntries sets the number of answers the semantic cache keeps
+func WithMaxEntries(n int) SemanticCacheOption {
+	return func(o *SemanticCacheOptions) {
+		o.MaxEntries = n
+	}
+}
+
+// WithSemanticTTL sets how long the semantic cache keeps answers
+func WithSemanticTTL(ttl time.Duration) SemanticCacheOption {
+	return func(o *SemanticCacheOptions) {
+		o.TTL = ttl
+	}
+}
+
+// SemanticMatch describes the cached prompt that answered a request.
+type SemanticMatch struct {
+	// Prompt is the cached prompt.
+	Prompt string
+	// Score is the cosine similarity between the cached and requested
+	// prompts.
+	Score float64
+}
+
+// SemanticCache answers Generate and Chat requests whose prompt is close
+// in meaning to one answered before, e.g. paraphrases of the same
+// question. Prompts are embedded with an embedding model and compared by
+// cosine similarity. Only requests that are otherwise identical share
+// answers: same model, system prompt and options for Generate, and the
+// same earlier messages for Chat, where the last user message is the
+// prompt. Requests with images are not cached.
+type SemanticCache struct {
+	api   API
+	model string
+	opts  *SemanticCacheOptions
+	now   func() time.Time
+
+	mu      sync.Mutex
+	entries []*semanticEntry
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+type semanticEntry struct {
+	scope   string
+	prompt  string
+	vector  []float64
+	answer  []byte
+	expires time.Time
+}
+
+// NewSemanticCache creates a SemanticCache that calls api and embeds
+// prompts with embeddingModel.
+func NewSemanticCache(api API, embeddingModel string, options ...SemanticCacheOption) *SemanticCache {
+	opts := &SemanticCacheOptions{
+		Threshold:  0.95,
+		MaxEntries: 1000,
+	}
+	for _, opt := range options {
+		opt(opts)
+	}
+	return &SemanticCache{api: api, model: embeddingModel, opts: opts, now: time.Now}
+}
+
+// Stats returns the hit and miss counters.
+func (s *SemanticCache) Stats() CacheStats {
+	return CacheStats{Hits: s.hits.Load(), Misses: s.misses.Load()}
+}
+
+// Len returns the number of cached answers.
+func (s *SemanticCache) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// semanticScope hashes the parts of a request that must match exactly.
+func semanticScope(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// embed returns the normalized embedding of prompt.
+func (s *SemanticCache) embed(ctx context.Context, prompt string) ([]float64, error) {
+	resp, err := s.api.Embeddings(ctx, &EmbeddingRequest{Model: s.model, Prompt: prompt})
+	if err != nil {
+		return nil, fmt.Errorf("embedding prompt: %w", err)
+	}
+	vector := make([]float64, len(resp.Embedding))
+	var norm float64
+	for i, x := range resp.Embedding {
+		vector[i] = float64(x)
+		norm += vector[i] * vector[i]
+	}
+	if norm == 0 {
+		return vector, nil
+	}
+	norm = math.Sqrt(norm)
+	for i := range vector {
+		vector[i] /= norm
+	}
+	return vector, nil
+}
+
+// lookup returns the best cached answer for the scope above the threshold.
+func (s *SemanticCache) lookup(scope string, vector []float64) (*semanticEntry, float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	var best *semanticEntry
+	bestScore := math.Inf(-1)
+	live := s.entries[:0]
+	for _, e := range s.entries {
+		if !e.expires.IsZero() && now.After(e.expires) {
+			continue
+		}
+		live = append(live, e)
+		if e.scope != scope || len(e.vector) != len(vector) {
+			continue
+		}
+		var score float64
+		for i := range vector {
+			score += e.vector[i] * vector[i]
+		}
+		if score > bestScore {
+			best, bestScore = e, score
+		}
+	}
+	s.entries = live
+	if best == nil || bestScore < s.opts.Threshold {
+		return nil, 0
+	}
+	return best, bestScore
+}
+
+// store caches answer for the prompt, replacing an earlier answer to the
+// same prompt and evicting the oldest entries.
+func (s *SemanticCache) store(scope, prompt string, vector []float64, answer interface{}) {
+	b, err := json.Marshal(answer)
+	if err != nil {
+		return
+	}
+	e := &semanticEntry{scope: scope, prompt: prompt, vector: vector, answer: b}
+	if s.opts.TTL > 0 {
+		e.expires = s.now().Add(s.opts.TTL)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := s.entries[:0]
+	for _, old := range s.entries {
+		if old.scope != scope || old.prompt != prompt {
+			entries = append(entries, old)
+		}
+	}
+	s.entries = append(entries, e)
+	if s.opts.MaxEntries > 0 && len(s.entries) > s.opts.MaxEntries {
+		s.entries = append(s.entries[:0:0], s.entries[len(s.entries)-s.opts.MaxEntries:]...)
+	}
+}
+
+// semanticCall answers prompt from the cache or with call, storing the
+// answer. The returned match is nil when call was used. If the prompt
+// cannot be embedded, call answers without the cache.
+func semanticCall[T any](ctx context.Context, s *SemanticCache, scopeKey interface{}, prompt string, call func() (*T, error)) (*T, *SemanticMatch, error) {
+	scope, err := semanticScope(scopeKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	vector, err := s.embed(ctx, prompt)
+	if err != nil {
+		s.misses.Add(1)
+		resp, err := call()
+		return resp, nil, err
+	}
+	if e, score := s.lookup(scope, vector); e != nil {
+		var resp T
+		if err := json.Unmarshal(e.answer, &resp); err == nil {
+			s.hits.Add(1)
+			return &resp, &SemanticMatch{Prompt: e.prompt, Score: score}, nil
+		}
+	}
+
+	s.misses.Add(1)
+	resp, err := call()
+	if err != nil {
+		return nil, nil, err
+	}
+	s.store(scope, prompt, vector, resp)
+	return resp, nil, nil
+}
+
+// Generate answers req from the cache if a similar prompt was answered
+// before, returning the match, or calls the API otherwise.
+func (s *SemanticCache) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, *SemanticMatch, error) {
+	if len(req.Images) > 0 || req.Prompt == "" {
+		resp, err := s.api.Generate(ctx, req)
+		return resp, nil, err
+	}
+	scope := *req
+	scope.Prompt = ""
+	scope.KeepAlive = 0
+	scope.Stream = false
+	return semanticCall(ctx, s, scope, req.Prompt, func() (*GenerateResponse, error) {
+		return s.api.Generate(ctx, req)
+	})
+}
+
+// Chat answers req from the cache if a similar last user message was
+// answered before in the same conversation, returning the match, or calls
+// the API otherwise.
+func (s *SemanticCache) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, *SemanticMatch, error) {
+	n := len(req.Messages)
+	cacheable := n > 0 && req.Messages[n-1].Role == UserRole && req.Messages[n-1].Content != ""
+	for _, m := range req.Messages {
+		if len(m.Images) > 0 {
+			cacheable = false
+		}
+	}
+	if !cacheable {
+		resp, err := s.api.Chat(ctx, req)
+		return resp, nil, err
+	}
+	scope := *req
+	scope.Messages = req.Messages[:n-1]
+	scope.KeepAlive = 0
+	scope.Stream = false
+	return semanticCall(ctx, s, scope, req.Messages[n-1].Content, func() (*ChatResponse, error) {
+		return s.api.Chat(ctx, req)
+	})
+}
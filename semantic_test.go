@@ -0,0 +1,172 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/wiseinf/ollama-go/ollamatest"
+)
+
+// withEmbeddings embeds prompts by looking them up in vectors, so that
+// tests control which prompts are similar.
+func withEmbeddings(vectors map[string][]float32) testServerOption {
+	return withHandler("/api/embeddings", func(w http.ResponseWriter, r *http.Request) {
+		var req EmbeddingRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(EmbeddingResponse{Embedding: vectors[req.Prompt]})
+	})
+}
+
+func TestSemanticCacheGenerate(t *testing.T) {
+	server, client := newTestServer(t, withEmbeddings(map[string][]float32{
+		"How do I reset my password?":       {1, 0, 0},
+		"how can I reset my password":       {0.99, 0.1, 0},
+		"What are your opening hours?":      {0, 1, 0},
+		"How do I reset my password please": {2, 0.02, 0},
+	}))
+	cache := NewSemanticCache(client, "nomic-embed-text", WithSimilarityThreshold(0.9))
+	ctx := context.Background()
+
+	tests := []struct {
+		prompt    string
+		system    string
+		wantMatch string
+	}{
+		{"How do I reset my password?", "", ""},
+		{"how can I reset my password", "", "How do I reset my password?"},
+		{"What are your opening hours?", "", ""},
+		{"How do I reset my password please", "", "How do I reset my password?"},
+		{"how can I reset my password", "Answer in French.", ""},
+	}
+	for _, tt := range tests {
+		resp, match, err := cache.Generate(ctx, &GenerateRequest{Model: "llama3.2:1b", Prompt: tt.prompt, System: tt.system})
+		if err != nil {
+			t.Fatalf("Generate(%q) error = %v", tt.prompt, err)
+		}
+		if resp.Response != server.Reply {
+			t.Errorf("Generate(%q) response = %q, want %q", tt.prompt, resp.Response, server.Reply)
+		}
+		gotMatch := ""
+		if match != nil {
+			gotMatch = match.Prompt
+			if match.Score < 0.9 || match.Score > 1+1e-9 {
+				t.Errorf("Generate(%q) score = %v, want within [0.9, 1]", tt.prompt, match.Score)
+			}
+		}
+		if gotMatch != tt.wantMatch {
+			t.Errorf("Generate(%q) matched %q, want %q", tt.prompt, gotMatch, tt.wantMatch)
+		}
+	}
+	if hits := server.Hits("/api/generate"); hits != 3 {
+		t.Errorf("generate hits = %d, want 3", hits)
+	}
+	if got := cache.Stats(); got != (CacheStats{Hits: 2, Misses: 3}) {
+		t.Errorf("Stats() = %+v, want 2 hits and 3 misses", got)
+	}
+}
+
+func TestSemanticCacheChat(t *testing.T) {
+	server, client := newTestServer(t, withEmbeddings(map[string][]float32{
+		"Hi":    {1, 0},
+		"Hello": {0.98, 0.05},
+	}))
+	cache := NewSemanticCache(client, "nomic-embed-text")
+	ctx := context.Background()
+	chat := func(messages ...ChatMessage) *SemanticMatch {
+		t.Helper()
+		_, match, err := cache.Chat(ctx, &ChatRequest{Model: "llama3.2:1b", Messages: messages})
+		if err != nil {
+			t.Fatalf("Chat() error = %v", err)
+		}
+		return match
+	}
+
+	chat(ChatMessage{Role: UserRole, Content: "Hi"})
+	if match := chat(ChatMessage{Role: UserRole, Content: "Hello"}); match == nil || match.Prompt != "Hi" {
+		t.Errorf("match = %+v, want Hi", match)
+	}
+	// A different conversation does not share answers.
+	if match := chat(ChatMessage{Role: SystemRole, Content: "Be brief."}, ChatMessage{Role: UserRole, Content: "Hello"}); match != nil {
+		t.Errorf("match = %+v across conversations, want nil", match)
+	}
+	// Images bypass the cache.
+	if match := chat(ChatMessage{Role: UserRole, Content: "Hi", Images: []string{"aGk="}}); match != nil {
+		t.Errorf("match = %+v with images, want nil", match)
+	}
+	if hits := server.Hits("/api/chat"); hits != 3 {
+		t.Errorf("chat hits = %d, want 3", hits)
+	}
+}
+
+func TestSemanticCacheEviction(t *testing.T) {
+	vectors := map[string][]float32{}
+	for i := 0; i < 3; i++ {
+		v := make([]float32, 3)
+		v[i] = 1
+		vectors[fmt.Sprint(i)] = v
+	}
+	_, client := newTestServer(t, withEmbeddings(vectors))
+	now := time.Unix(0, 0)
+	cache := NewSemanticCache(client, "nomic-embed-text", WithMaxEntries(2), WithSemanticTTL(time.Minute))
+	cache.now = func() time.Time { return now }
+	generate := func(prompt string) *SemanticMatch {
+		t.Helper()
+		_, match, err := cache.Generate(context.Background(), &GenerateRequest{Model: "llama3.2:1b", Prompt: prompt})
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		return match
+	}
+
+	generate("0")
+	generate("1")
+	generate("2")
+	if cache.Len() != 2 || generate("0") != nil {
+		t.Errorf("oldest entry was not evicted")
+	}
+	now = now.Add(2 * time.Minute)
+	if generate("2") != nil {
+		t.Error("expired entry matched")
+	}
+	if cache.Len() != 1 {
+		t.Errorf("Len() = %d, want expired entries dropped", cache.Len())
+	}
+}
+
+func TestSemanticCacheEmbeddingFailure(t *testing.T) {
+	server, client := newTestServer(t, withEmbeddings(map[string][]float32{"Hi": {1, 0}}))
+	server.Inject("/api/embeddings", ollamatest.Fault{Status: http.StatusNotFound, ErrorMessage: "model not found"})
+	cache := NewSemanticCache(client, "nomic-embed-text")
+
+	resp, match, err := cache.Generate(context.Background(), &GenerateRequest{Model: "llama3.2:1b", Prompt: "Hi"})
+	if err != nil {
+		t.Fatalf("Generate() error = %v, want the model to answer", err)
+	}
+	if resp.Response != server.Reply || match != nil {
+		t.Errorf("Generate() = %q, %+v, want the model's reply", resp.Response, match)
+	}
+	if cache.Len() != 0 {
+		t.Errorf("Len() = %d, want nothing cached without an embedding", cache.Len())
+	}
+}
+
+func TestSemanticCacheStoreDedupes(t *testing.T) {
+	server, client := newTestServer(t, withEmbeddings(map[string][]float32{"Hi": {1, 0}, "Bye": {0, 1}}))
+	// No prompt is similar enough to match, so every call misses.
+	cache := NewSemanticCache(client, "nomic-embed-text", WithSimilarityThreshold(2))
+	for _, prompt := range []string{"Hi", "Bye", "Hi", "Hi"} {
+		if _, _, err := cache.Generate(context.Background(), &GenerateRequest{Model: "llama3.2:1b", Prompt: prompt}); err != nil {
+			t.Fatalf("Generate(%q) error = %v", prompt, err)
+		}
+	}
+	if hits := server.Hits("/api/generate"); hits != 4 {
+		t.Errorf("generate hits = %d, want 4", hits)
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Len() = %d, want one entry per prompt", cache.Len())
+	}
+}
@@ -0,0 +1,195 @@
+package ollama
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Tracer starts spans around client activity. Implement it to adapt the
+// client to a tracing library. The client starts a span per API call, a
+// child span per HTTP attempt, and for streaming calls a child span for the
+// lifetime of the stream.
+type Tracer interface {
+	// Start starts a span as a child of any span in ctx and returns a
+	// context carrying the new span.
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is a unit of traced work.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	// SpanContext identifies the span for propagation. It may be the zero
+	// value if the tracer does not propagate.
+	SpanContext() SpanContext
+	End()
+}
+
+// Attribute is a key-value pair recorded on a span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Attr creates an Attribute.
+func Attr(key string, value interface{}) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// SpanContext is the W3C trace context of a span.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+}
+
+// IsValid reports whether the trace and span ids are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// TraceParent formats sc as a W3C traceparent header value.
+func (sc SpanContext) TraceParent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", hex.EncodeToString(sc.TraceID[:]), hex.EncodeToString(sc.SpanID[:]), flags)
+}
+
+// ParseTraceParent parses a W3C traceparent header value.
+func ParseTraceParent(s string) (SpanContext, error) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return sc, fmt.Errorf("invalid traceparent %q", s)
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return sc, fmt.Errorf("invalid traceparent %q", s)
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, fmt.Errorf("invalid traceparent %q", s)
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, fmt.Errorf("invalid traceparent trace id: %w", err)
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, fmt.Errorf("invalid traceparent span id: %w", err)
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, fmt.Errorf("invalid traceparent flags: %w", err)
+	}
+	sc.Sampled = flags[0]&1 == 1
+	if !sc.IsValid() {
+		return sc, errors.New("invalid traceparent: zero trace or span id")
+	}
+	return sc, nil
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns a context carrying sc. Requests made with
+// the context propagate sc in the traceparent header, even without a Tracer.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context stored by
+// ContextWithSpanContext.
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok
+}
+
+// WithTracer traces client calls with t
+func WithTracer(t Tracer) func(*ClientOptions) {
+	return func(o *ClientOptions) {
+		o.Tracer = t
+	}
+}
+
+// noopTracer starts spans that record nothing.
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+func (noopSpan) RecordError(error)          {}
+func (noopSpan) SpanContext() SpanContext   { return SpanContext{} }
+func (noopSpan) End()                       {}
+
+// traceParent returns the traceparent to send for span, falling back to a
+// span context stored in ctx.
+func traceParent(ctx context.Context, span Span) string {
+	if sc := span.SpanContext(); sc.IsValid() {
+		return sc.TraceParent()
+	}
+	if sc, ok := SpanContextFromContext(ctx); ok && sc.IsValid() {
+		return sc.TraceParent()
+	}
+	return ""
+}
+
+// startCallSpan starts the span covering a whole call and arranges for it,
+// and for streams the stream span, to end with the call.
+func (c *Client) startCallSpan(ctx context.Context, call *Call) context.Context {
+	ctx, span := c.tracer.Start(ctx, "ollama "+call.Endpoint,
+		Attr("http.method", call.Method),
+		Attr("ollama.endpoint", call.Endpoint),
+		Attr("ollama.model", call.Model()),
+		Attr("ollama.stream", call.Stream),
+	)
+	call.OnEnd(func(call *Call) {
+		span.SetAttributes(callAttributes(call)...)
+		if call.Err != nil {
+			span.RecordError(call.Err)
+		}
+		span.End()
+	})
+	return ctx
+}
+
+// startStreamSpan starts the span covering the lifetime of a stream once
+// its response has arrived.
+func (c *Client) startStreamSpan(ctx context.Context, call *Call) {
+	_, span := c.tracer.Start(ctx, "ollama stream "+call.Endpoint, Attr("ollama.model", call.Model()))
+	call.OnEnd(func(call *Call) {
+		if call.TTFT > 0 {
+			span.SetAttributes(Attr("ollama.ttft_ms", call.TTFT.Milliseconds()))
+		}
+		if call.Err != nil {
+			span.RecordError(call.Err)
+		}
+		span.End()
+	})
+}
+
+// callAttributes describes the outcome of a call.
+func callAttributes(call *Call) []Attribute {
+	attrs := []Attribute{
+		Attr("http.status_code", call.StatusCode),
+		Attr("ollama.attempts", call.Attempts),
+		Attr("ollama.duration_ms", call.Duration.Milliseconds()),
+	}
+	if r, ok := call.Result.(interface{ Usage() Usage }); ok {
+		u := r.Usage()
+		attrs = append(attrs,
+			Attr("ollama.prompt_tokens", u.PromptTokens),
+			Attr("ollama.eval_tokens", u.EvalTokens),
+			Attr("ollama.load_duration_ms", u.LoadDuration.Milliseconds()),
+			Attr("ollama.eval_duration_ms", u.EvalDuration.Milliseconds()),
+		)
+	}
+	if call.Stream && call.TTFT > 0 {
+		attrs = append(attrs, Attr("ollama.ttft_ms", call.TTFT.Milliseconds()))
+	}
+	return attrs
+}
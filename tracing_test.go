@@ -0,0 +1,194 @@
+package ollama
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wiseinf/ollama-go/ollamatest"
+)
+
+// recordingTracer records spans for inspection.
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	name   string
+	parent SpanContext
+	sc     SpanContext
+	attrs  map[string]interface{}
+	err    error
+	ended  bool
+	tracer *recordingTracer
+}
+
+type recordedSpanKey struct{}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := &recordedSpan{name: name, attrs: make(map[string]interface{}), tracer: t}
+	if parent, ok := ctx.Value(recordedSpanKey{}).(*recordedSpan); ok {
+		s.parent = parent.sc
+		s.sc.TraceID = parent.sc.TraceID
+	} else {
+		s.sc.TraceID = [16]byte{1}
+	}
+	s.sc.SpanID = [8]byte{byte(len(t.spans) + 1)}
+	s.sc.Sampled = true
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+	t.spans = append(t.spans, s)
+	return context.WithValue(ctx, recordedSpanKey{}, s), s
+}
+
+func (t *recordingTracer) find(name string) []*recordedSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var found []*recordedSpan
+	for _, s := range t.spans {
+		if s.name == name {
+			found = append(found, s)
+		}
+	}
+	return found
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...Attribute) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordedSpan) RecordError(err error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.err = err
+}
+
+func (s *recordedSpan) SpanContext() SpanContext { return s.sc }
+
+func (s *recordedSpan) End() {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.ended = true
+}
+
+func TestTracerSpans(t *testing.T) {
+	tracer := &recordingTracer{}
+	server, client := newTestServer(t, withClientOptions(WithRetryWaitTime(time.Millisecond), WithTracer(tracer)))
+
+	var traceparents []string
+	var mu sync.Mutex
+	server.Inject("/api/generate", ollamatest.Fault{Status: http.StatusBadGateway})
+	inner := func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		mu.Unlock()
+		io.WriteString(w, `{"model":"llama3.2:1b","response":"Hi","done":true,"prompt_eval_count":3,"eval_count":7}`)
+	}
+	server.Handle("/api/generate", inner)
+
+	if _, err := client.Generate(context.Background(), &GenerateRequest{Model: "llama3.2:1b", Prompt: "Hi"}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	calls := tracer.find("ollama /api/generate")
+	if len(calls) != 1 {
+		t.Fatalf("call spans = %d, want 1", len(calls))
+	}
+	call := calls[0]
+	if !call.ended || call.attrs["ollama.model"] != "llama3.2:1b" || call.attrs["ollama.attempts"] != 2 {
+		t.Errorf("call span = %+v", call)
+	}
+	if call.attrs["ollama.prompt_tokens"] != 3 || call.attrs["ollama.eval_tokens"] != 7 || call.attrs["http.status_code"] != 200 {
+		t.Errorf("call span attributes = %v, want token counts and status", call.attrs)
+	}
+
+	attempts := tracer.find("ollama attempt")
+	if len(attempts) != 2 {
+		t.Fatalf("attempt spans = %d, want 2", len(attempts))
+	}
+	for i, a := range attempts {
+		if a.parent != call.sc || !a.ended || a.attrs["ollama.attempt"] != i+1 {
+			t.Errorf("attempt span %d = %+v, want a child of the call span", i, a)
+		}
+	}
+	if attempts[0].attrs["http.status_code"] != http.StatusBadGateway {
+		t.Errorf("first attempt status = %v, want 502", attempts[0].attrs["http.status_code"])
+	}
+	if len(traceparents) != 1 || traceparents[0] != attempts[1].sc.TraceParent() {
+		t.Errorf("traceparent headers = %v, want the second attempt span %s", traceparents, attempts[1].sc.TraceParent())
+	}
+}
+
+func TestTracerStreamSpan(t *testing.T) {
+	tracer := &recordingTracer{}
+	_, client := newTestServer(t, withClientOptions(WithTracer(tracer)))
+
+	stream, err := client.GenerateStream(context.Background(), &GenerateRequest{Model: "llama3.2:1b", Prompt: "Hi"})
+	if err != nil {
+		t.Fatalf("GenerateStream() error = %v", err)
+	}
+	if spans := tracer.find("ollama stream /api/generate"); len(spans) != 1 || spans[0].ended {
+		t.Fatalf("stream span should be open while the stream is read: %+v", spans)
+	}
+	for range stream {
+	}
+
+	span := tracer.find("ollama stream /api/generate")[0]
+	call := tracer.find("ollama /api/generate")[0]
+	if !span.ended || span.parent != call.sc || span.attrs["ollama.ttft_ms"] == nil {
+		t.Errorf("stream span = %+v, want an ended child of the call span with TTFT", span)
+	}
+	if !call.ended || call.attrs["ollama.eval_tokens"] != 20 {
+		t.Errorf("call span = %+v, want the final chunk usage", call)
+	}
+}
+
+func TestTraceParentPropagationWithoutTracer(t *testing.T) {
+	var got string
+	_, client := newTestServer(t, withHandler("/api/tags", func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("traceparent")
+		io.WriteString(w, `{"models":[]}`)
+	}))
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceParent(traceparent)
+	if err != nil {
+		t.Fatalf("ParseTraceParent() error = %v", err)
+	}
+	if sc.TraceParent() != traceparent {
+		t.Errorf("TraceParent() = %s, want %s", sc.TraceParent(), traceparent)
+	}
+
+	if _, err := client.ListModels(ContextWithSpanContext(context.Background(), sc)); err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	if got != traceparent {
+		t.Errorf("traceparent header = %q, want %q", got, traceparent)
+	}
+}
+
+func TestParseTraceParentInvalid(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		if _, err := ParseTraceParent(s); err == nil {
+			t.Errorf("ParseTraceParent(%q) error = nil", s)
+		}
+	}
+}
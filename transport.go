@@ -0,0 +1,69 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// unixBaseURL is the base URL of requests sent over a unix socket. The host
+// only fills the Host header; the dialer ignores it.
+const unixBaseURL = "http://unix"
+
+// WithDialer dials connections to the server with dial instead of the
+// default dialer, e.g. to route through a tunnel. It is ignored if a custom
+// HTTP client uses a transport other than *http.Transport.
+func WithDialer(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(*ClientOptions) {
+	return func(o *ClientOptions) {
+		o.Dialer = dial
+	}
+}
+
+// newHTTPClient returns the HTTP client and base URL to use for opts. A
+// unix:///path/to/socket base URL is served by a transport dialing the
+// socket; it is an error if a custom HTTP client's transport cannot be
+// made to dial it.
+func newHTTPClient(opts *ClientOptions) (*http.Client, string, error) {
+	baseURL := strings.TrimSuffix(opts.BaseURL, "/")
+	dial := opts.Dialer
+	socket, isUnix := strings.CutPrefix(baseURL, "unix://")
+	if isUnix {
+		baseURL = unixBaseURL
+		next := dial
+		if next == nil {
+			next = (&net.Dialer{}).DialContext
+		}
+		dial = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return next(ctx, "unix", socket)
+		}
+	}
+
+	if opts.HTTPClient != nil && dial == nil {
+		return opts.HTTPClient, baseURL, nil
+	}
+	client := &http.Client{Timeout: opts.Timeout}
+	if opts.HTTPClient != nil {
+		c := *opts.HTTPClient
+		client = &c
+	}
+	if dial != nil {
+		var transport *http.Transport
+		switch t := client.Transport.(type) {
+		case nil:
+			transport = http.DefaultTransport.(*http.Transport).Clone()
+		case *http.Transport:
+			transport = t.Clone()
+		default:
+			if isUnix {
+				return client, baseURL, fmt.Errorf("cannot reach unix socket %s: custom HTTP client transport %T is not an *http.Transport", socket, t)
+			}
+		}
+		if transport != nil {
+			transport.DialContext = dial
+			client.Transport = transport
+		}
+	}
+	return client, baseURL, nil
+}
@@ -0,0 +1,130 @@
+package ollama
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wiseinf/ollama-go/ollamatest"
+)
+
+// serveUnix serves the fake server's handler on a unix socket and returns
+// the socket path.
+func serveUnix(t *testing.T, server *ollamatest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ollama.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	srv := &http.Server{Handler: server.Config.Handler}
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+	return path
+}
+
+func TestUnixSocket(t *testing.T) {
+	server := ollamatest.NewServer()
+	defer server.Close()
+	socket := serveUnix(t, server)
+
+	tests := []struct {
+		name string
+		opts []ClientOption
+	}{
+		{"default client", nil},
+		{"custom client", []ClientOption{WithHTTPClient(&http.Client{Timeout: time.Minute})}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(append([]ClientOption{WithBaseURL("unix://" + socket)}, tt.opts...)...)
+			ctx := context.Background()
+
+			resp, err := client.Generate(ctx, &GenerateRequest{Model: "llama3.2:1b", Prompt: "Hi"})
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			if resp.Response != server.Reply {
+				t.Errorf("Response = %q, want %q", resp.Response, server.Reply)
+			}
+
+			stream, err := client.ChatStream(ctx, &ChatRequest{Model: "llama3.2:1b", Messages: []ChatMessage{{Role: UserRole, Content: "Hi"}}})
+			if err != nil {
+				t.Fatalf("ChatStream() error = %v", err)
+			}
+			var text string
+			for r := range stream {
+				if r.Error != nil {
+					t.Fatalf("stream error = %v", r.Error)
+				}
+				text += r.ChatResponse.Message.Content
+			}
+			if text != server.Reply {
+				t.Errorf("streamed %q, want %q", text, server.Reply)
+			}
+		})
+	}
+	if server.Hits("/api/generate") != 2 || server.Hits("/api/chat") != 2 {
+		t.Errorf("hits = %d generate, %d chat, want 2 each", server.Hits("/api/generate"), server.Hits("/api/chat"))
+	}
+}
+
+func TestWithDialer(t *testing.T) {
+	server := ollamatest.NewServer()
+	defer server.Close()
+	socket := serveUnix(t, server)
+
+	var dials atomic.Int32
+	var d net.Dialer
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dials.Add(1)
+		return d.DialContext(ctx, network, addr)
+	}
+
+	client := NewClient(WithBaseURL(server.URL), WithDialer(dial))
+	if _, err := client.ListModels(context.Background()); err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	if dials.Load() != 1 {
+		t.Errorf("dials = %d, want 1", dials.Load())
+	}
+
+	client = NewClient(WithBaseURL("unix://"+socket), WithDialer(dial))
+	if _, err := client.ListModels(context.Background()); err != nil {
+		t.Fatalf("ListModels() over unix socket error = %v", err)
+	}
+	if dials.Load() != 2 {
+		t.Errorf("dials = %d, want the custom dialer used for the socket", dials.Load())
+	}
+}
+
+// roundTripperFunc is an http.RoundTripper that is not an *http.Transport.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestUnixSocketCustomTransport(t *testing.T) {
+	custom := &http.Client{Transport: roundTripperFunc(http.DefaultTransport.RoundTrip)}
+	client := NewClient(WithBaseURL("unix:///run/ollama/ollama.sock"), WithHTTPClient(custom))
+	if _, err := client.ListModels(context.Background()); err == nil || !strings.Contains(err.Error(), "not an *http.Transport") {
+		t.Errorf("ListModels() error = %v, want the transport rejected", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("OLLAMA_CONFIG", path)
+	t.Setenv("OLLAMA_HOST", "unix:///run/ollama/ollama.sock")
+	if _, err := NewClientFromEnv(WithHTTPClient(custom)); err == nil || !strings.Contains(err.Error(), "not an *http.Transport") {
+		t.Errorf("NewClientFromEnv() error = %v, want the transport rejected", err)
+	}
+}
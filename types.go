@@ -1,7 +1,9 @@
 package ollama
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -127,6 +129,9 @@ type ChatStreamResponse struct {
 }
 
 // ModelInfo represents information about a model
+//
+// Deprecated: ListModels returns ListedModel, ListRunningModels returns
+// RunningModel and ShowModel returns ShowModelResponse.
 type ModelInfo struct {
 	Name       string                 `json:"name"`
 	Modified   time.Time              `json:"modified"`
@@ -139,11 +144,202 @@ type ModelInfo struct {
 	Template   string                 `json:"template,omitempty"`
 }
 
-// CreateModelRequest represents a request to create a model
+// normalizeModelName adds the default "latest" tag to a model name without
+// one, so that "llama3" and "llama3:latest" compare equal. A registry port,
+// as in "localhost:5000/llama3", is not a tag.
+func normalizeModelName(name string) string {
+	if name == "" || strings.Contains(name[strings.LastIndex(name, "/")+1:], ":") {
+		return name
+	}
+	return name + ":latest"
+}
+
+// ListedModel is a local model returned by ListModels
+type ListedModel struct {
+	Name       string       `json:"name"`
+	Model      string       `json:"model"`
+	ModifiedAt time.Time    `json:"modified_at"`
+	Size       int64        `json:"size"`
+	Digest     string       `json:"digest"`
+	Details    ModelDetails `json:"details"`
+}
+
+// RunningModel is a model loaded in memory, returned by ListRunningModels
+type RunningModel struct {
+	Name    string       `json:"name"`
+	Model   string       `json:"model"`
+	Size    int64        `json:"size"`
+	Digest  string       `json:"digest"`
+	Details ModelDetails `json:"details"`
+	// ExpiresAt is when the model will be unloaded if it stays idle.
+	ExpiresAt time.Time `json:"expires_at"`
+	// SizeVRAM is the part of Size held in GPU memory.
+	SizeVRAM      int64 `json:"size_vram"`
+	ContextLength int   `json:"context_length"`
+}
+
+// VRAMFraction returns the fraction of the model held in GPU memory, from
+// 0 when it runs on the CPU to 1 when it runs fully on the GPU.
+func (m RunningModel) VRAMFraction() float64 {
+	if m.Size <= 0 {
+		return 0
+	}
+	return float64(m.SizeVRAM) / float64(m.Size)
+}
+
+// TimeUntilUnload returns how long the model stays loaded if it is not
+// used, or 0 if it is due to be unloaded.
+func (m RunningModel) TimeUntilUnload() time.Duration {
+	if d := time.Until(m.ExpiresAt); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// ModelDetails describes the format and size of a model
+type ModelDetails struct {
+	ParentModel       string   `json:"parent_model,omitempty"`
+	Format            string   `json:"format,omitempty"`
+	Family            string   `json:"family,omitempty"`
+	Families          []string `json:"families,omitempty"`
+	ParameterSize     string   `json:"parameter_size,omitempty"`
+	QuantizationLevel string   `json:"quantization_level,omitempty"`
+}
+
+// Capability is a feature a model supports
+type Capability string
+
+const (
+	CapabilityCompletion Capability = "completion"
+	CapabilityTools      Capability = "tools"
+	CapabilityInsert     Capability = "insert"
+	CapabilityVision     Capability = "vision"
+	CapabilityEmbedding  Capability = "embedding"
+	CapabilityThinking   Capability = "thinking"
+)
+
+// ModelTensor describes a tensor of a model, returned by verbose ShowModel
+type ModelTensor struct {
+	Name  string   `json:"name"`
+	Type  string   `json:"type"`
+	Shape []uint64 `json:"shape"`
+}
+
+// ShowModelResponse represents a response from the show endpoint
+type ShowModelResponse struct {
+	License    string        `json:"license,omitempty"`
+	Modelfile  string        `json:"modelfile,omitempty"`
+	Parameters string        `json:"parameters,omitempty"`
+	Template   string        `json:"template,omitempty"`
+	System     string        `json:"system,omitempty"`
+	Details    ModelDetails  `json:"details"`
+	Messages   []ChatMessage `json:"messages,omitempty"`
+	// ModelInfo holds the model's metadata, keyed like
+	// "general.architecture" or "llama.context_length".
+	ModelInfo     map[string]interface{} `json:"model_info,omitempty"`
+	ProjectorInfo map[string]interface{} `json:"projector_info,omitempty"`
+	Tensors       []ModelTensor          `json:"tensors,omitempty"`
+	Capabilities  []Capability           `json:"capabilities,omitempty"`
+	ModifiedAt    time.Time              `json:"modified_at"`
+}
+
+// Architecture returns the model architecture, e.g. "llama"
+func (r *ShowModelResponse) Architecture() string {
+	arch, _ := r.ModelInfo["general.architecture"].(string)
+	return arch
+}
+
+// modelInfoInt returns a numeric model_info value, or 0 if it is missing.
+func (r *ShowModelResponse) modelInfoInt(key string) int64 {
+	switch v := r.ModelInfo[key].(type) {
+	case float64:
+		return int64(v)
+	case json.Number:
+		n, _ := v.Int64()
+		return n
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	}
+	return 0
+}
+
+// ParameterCount returns the number of parameters of the model
+func (r *ShowModelResponse) ParameterCount() int64 {
+	return r.modelInfoInt("general.parameter_count")
+}
+
+// ContextLength returns the context length the model was trained with
+func (r *ShowModelResponse) ContextLength() int {
+	return int(r.modelInfoInt(r.Architecture() + ".context_length"))
+}
+
+// EmbeddingLength returns the size of the model's embeddings
+func (r *ShowModelResponse) EmbeddingLength() int {
+	return int(r.modelInfoInt(r.Architecture() + ".embedding_length"))
+}
+
+// HasCapability reports whether the model supports c
+func (r *ShowModelResponse) HasCapability(c Capability) bool {
+	for _, have := range r.Capabilities {
+		if have == c {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsTools reports whether the model can call tools
+func (r *ShowModelResponse) SupportsTools() bool {
+	return r.HasCapability(CapabilityTools)
+}
+
+// SupportsVision reports whether the model accepts images. Servers that do
+// not report capabilities are checked for a vision projector.
+func (r *ShowModelResponse) SupportsVision() bool {
+	return r.HasCapability(CapabilityVision) || len(r.ProjectorInfo) > 0
+}
+
+// SupportsEmbedding reports whether the model produces embeddings
+func (r *ShowModelResponse) SupportsEmbedding() bool {
+	return r.HasCapability(CapabilityEmbedding)
+}
+
+// SupportsThinking reports whether the model can think before answering
+func (r *ShowModelResponse) SupportsThinking() bool {
+	return r.HasCapability(CapabilityThinking)
+}
+
+// CreateModelRequest represents a request to create a model, either from
+// an existing model or from files uploaded with PushBlob.
 type CreateModelRequest struct {
-	Name      string `json:"name"`
+	Model string `json:"model"`
+	// From is the model to build on.
+	From string `json:"from,omitempty"`
+	// Files and Adapters map file names to the digests of their blobs.
+	Files    map[string]string `json:"files,omitempty"`
+	Adapters map[string]string `json:"adapters,omitempty"`
+	// LocalFiles and LocalAdapters map file names to local paths. The
+	// files are uploaded with PushBlobFile before the model is created
+	// and added to Files and Adapters.
+	LocalFiles    map[string]string      `json:"-"`
+	LocalAdapters map[string]string      `json:"-"`
+	Template      string                 `json:"template,omitempty"`
+	License       []string               `json:"license,omitempty"`
+	System        string                 `json:"system,omitempty"`
+	Parameters    map[string]interface{} `json:"parameters,omitempty"`
+	Messages      []ChatMessage          `json:"messages,omitempty"`
+	// Quantize quantizes a non-quantized model, e.g. "q4_K_M".
+	Quantize string `json:"quantize,omitempty"`
+	// Stream controlled by the client.
+	Stream bool `json:"stream"`
+
+	// Deprecated: servers before 0.5.5 only; use Model, From and the
+	// other fields instead.
+	Name      string `json:"name,omitempty"`
 	Path      string `json:"path,omitempty"`
-	Modelfile string `json:"modelfile"`
+	Modelfile string `json:"modelfile,omitempty"`
 }
 
 // CopyModelRequest represents a request to copy a model
@@ -178,14 +374,46 @@ type EmbeddingResponse struct {
 	Embedding []float32 `json:"embedding"`
 }
 
-// ModelResponse represents a response containing model status
+// ModelResponse represents the progress of a pull, push or create
 type ModelResponse struct {
 	Status string `json:"status"`
+	// Digest, Total and Completed describe the blob being transferred.
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	// Error is set on the last response if the operation failed.
+	Error error `json:"-"`
 }
 
-// Duration is a wrapper around time.Duration
+// Duration is a wrapper around time.Duration used for keep_alive. The zero
+// Duration is not sent, so the server's default applies. Because zero means
+// "not set", the keep-alive of 0 that unloads a model is represented by the
+// UnloadNow marker, and "0"/"0s" decode to it. KeepForever and UnloadNow are
+// markers rather than lengths of time: use TimeDuration, not a conversion,
+// to do arithmetic on a Duration that may hold one.
 type Duration time.Duration
 
+const (
+	// KeepForever keeps a model loaded until it is unloaded. It is sent
+	// as -1; any negative keep_alive means the same to the server.
+	KeepForever Duration = -1
+	// UnloadNow unloads a model as soon as the request completes. It is
+	// sent as 0, which the zero Duration cannot express.
+	UnloadNow Duration = math.MinInt64
+)
+
+// TimeDuration returns d as a time.Duration, with UnloadNow as 0 and
+// KeepForever as -1ns.
+func (d Duration) TimeDuration() time.Duration {
+	switch {
+	case d == UnloadNow:
+		return 0
+	case d < 0:
+		return time.Duration(KeepForever)
+	}
+	return time.Duration(d)
+}
+
 const (
 	SecsPerMin  = 60.0
 	SecsPerHour = 3600.0
@@ -194,18 +422,20 @@ const (
 
 // MarshalJSON implements the json.Marshaler interface
 func (d Duration) MarshalJSON() ([]byte, error) {
-	durSecs := int64(time.Duration(d).Seconds())
-	if durSecs == 0 {
+	switch {
+	case d == UnloadNow:
+		return []byte("0"), nil
+	case d < 0:
+		return []byte("-1"), nil
+	case d == 0:
 		return []byte(`""`), nil
 	}
 
+	// Hours are the largest unit: the server parses keep_alive with
+	// time.ParseDuration, which has no day unit.
+	durSecs := int64(time.Duration(d) / time.Second)
 	var parts []string
 
-	if days := durSecs / SecsPerDay; days > 0 {
-		parts = append(parts, fmt.Sprintf("%dd", days))
-		durSecs %= SecsPerDay
-	}
-
 	if hours := durSecs / SecsPerHour; hours > 0 {
 		parts = append(parts, fmt.Sprintf("%dh", hours))
 		durSecs %= SecsPerHour
@@ -220,49 +450,91 @@ func (d Duration) MarshalJSON() ([]byte, error) {
 		parts = append(parts, fmt.Sprintf("%ds", durSecs))
 	}
 
+	frac := time.Duration(d) % time.Second
+	for _, unit := range []struct {
+		size time.Duration
+		name string
+	}{{time.Millisecond, "ms"}, {time.Microsecond, "us"}, {time.Nanosecond, "ns"}} {
+		if n := frac / unit.size; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d%s", n, unit.name))
+			frac %= unit.size
+		}
+	}
+
 	return []byte(fmt.Sprintf(`"%s"`, strings.Join(parts, ""))), nil
 }
 
+// UnmarshalJSON implements the json.Unmarshaler interface. It accepts a
+// number of seconds or a duration string such as "5m", "1.5h", "300ms" or
+// "2d12h". Negative values decode to KeepForever and zero to UnloadNow.
 func (d *Duration) UnmarshalJSON(b []byte) error {
-	s := strings.Trim(string(b), `"`)
+	if len(b) > 0 && b[0] != '"' {
+		var secs float64
+		if err := json.Unmarshal(b, &secs); err != nil {
+			return fmt.Errorf("invalid duration: %s", b)
+		}
+		switch {
+		case secs < 0:
+			*d = KeepForever
+		case secs == 0:
+			*d = UnloadNow
+		case secs >= math.MaxInt64/float64(time.Second):
+			*d = Duration(math.MaxInt64)
+		default:
+			*d = Duration(secs * float64(time.Second))
+		}
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
 	if s == "" {
 		*d = Duration(0)
 		return nil
 	}
 
-	var total time.Duration
-	current := ""
+	total, err := parseDuration(s)
+	if err != nil {
+		return err
+	}
+	switch {
+	case total < 0:
+		*d = KeepForever
+	case total == 0:
+		*d = UnloadNow
+	default:
+		*d = Duration(total)
+	}
+	return nil
+}
 
-	for i := 0; i < len(s); i++ {
-		if s[i] >= '0' && s[i] <= '9' {
-			current += string(s[i])
-			continue
+// parseDuration parses a Go duration string, optionally led by a number
+// of days as in "2d12h".
+func parseDuration(s string) (time.Duration, error) {
+	var days time.Duration
+	if i := strings.IndexByte(s, 'd'); i >= 0 {
+		n, err := strconv.ParseUint(s[:i], 10, 63)
+		if err != nil || n > uint64(math.MaxInt64/(24*time.Hour)) {
+			return 0, fmt.Errorf("invalid duration format: %s", s)
 		}
-
-		value, err := strconv.ParseInt(current, 10, 64)
-		if err != nil {
-			return err
+		days = time.Duration(n) * 24 * time.Hour
+		s = s[i+1:]
+		if s == "" {
+			return days, nil
 		}
-
-		switch s[i] {
-		case 's':
-			total += time.Duration(value) * time.Second
-		case 'm':
-			total += time.Duration(value) * time.Minute
-		case 'h':
-			total += time.Duration(value) * time.Hour
-		case 'd':
-			total += time.Duration(value) * 24 * time.Hour
-		default:
-			return fmt.Errorf("invalid duration unit: %c", s[i])
+		if s[0] == '-' || s[0] == '+' {
+			return 0, fmt.Errorf("invalid duration format: %s", s)
 		}
+	}
 
-		current = ""
+	rest, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
 	}
-	if current != "" {
-		return fmt.Errorf("invalid duration format: %s", s)
+	if days > 0 && rest > math.MaxInt64-days {
+		return 0, fmt.Errorf("invalid duration format: %s", s)
 	}
-
-	*d = Duration(total)
-	return nil
+	return days + rest, nil
 }
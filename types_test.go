@@ -23,7 +23,7 @@ func TestChatRequestMarshalling(t *testing.T) {
 		t.Fatalf("Failed to marshal ChatRequest: %v", err)
 	}
 
-	want := `{"model":"llama2","messages":[{"role":"user","content":"Hello"}],"keep_alive":"5m"}`
+	want := `{"model":"llama2","messages":[{"role":"user","content":"Hello"}],"stream":false,"keep_alive":"5m"}`
 
 	if string(got) != want {
 		t.Errorf("json.Marshal(req) = %v, want %v", string(got), want)
@@ -73,6 +73,61 @@ func TestDuration_UnmarshalJSON(t *testing.T) {
 			want:    Duration(60 * time.Hour),
 			wantErr: false,
 		},
+		{
+			name:  "fractional hours",
+			input: `"1.5h"`,
+			want:  Duration(90 * time.Minute),
+		},
+		{
+			name:  "milliseconds",
+			input: `"300ms"`,
+			want:  Duration(300 * time.Millisecond),
+		},
+		{
+			name:  "days and sub-seconds",
+			input: `"1d2h3m4s500ms"`,
+			want:  Duration(26*time.Hour + 3*time.Minute + 4*time.Second + 500*time.Millisecond),
+		},
+		{
+			name:  "seconds as a number",
+			input: `300`,
+			want:  Duration(5 * time.Minute),
+		},
+		{
+			name:  "fractional seconds as a number",
+			input: `0.25`,
+			want:  Duration(250 * time.Millisecond),
+		},
+		{
+			name:  "negative number",
+			input: `-1`,
+			want:  KeepForever,
+		},
+		{
+			name:  "negative string",
+			input: `"-5m"`,
+			want:  KeepForever,
+		},
+		{
+			name:  "zero number",
+			input: `0`,
+			want:  UnloadNow,
+		},
+		{
+			name:  "zero string",
+			input: `"0s"`,
+			want:  UnloadNow,
+		},
+		{
+			name:    "invalid number literal",
+			input:   `true`,
+			wantErr: true,
+		},
+		{
+			name:    "fractional days",
+			input:   `"1.5d"`,
+			wantErr: true,
+		},
 		{
 			name:    "invalid unit",
 			input:   `"5x"`,
@@ -119,6 +174,26 @@ func TestDuration_MarshalJSON(t *testing.T) {
 			d:    Duration(0),
 			want: `""`,
 		},
+		{
+			name: "keep forever",
+			d:    KeepForever,
+			want: `-1`,
+		},
+		{
+			name: "unload now",
+			d:    UnloadNow,
+			want: `0`,
+		},
+		{
+			name: "sub-second",
+			d:    Duration(300 * time.Millisecond),
+			want: `"300ms"`,
+		},
+		{
+			name: "seconds and nanoseconds",
+			d:    Duration(time.Second + 1500*time.Nanosecond),
+			want: `"1s1us500ns"`,
+		},
 		{
 			name: "simple seconds",
 			d:    Duration(45 * time.Second),
@@ -135,9 +210,9 @@ func TestDuration_MarshalJSON(t *testing.T) {
 			want: `"2h"`,
 		},
 		{
-			name: "simple days",
+			name: "days as hours",
 			d:    Duration(3 * 24 * time.Hour),
-			want: `"3d"`,
+			want: `"72h"`,
 		},
 		{
 			name: "minutes and seconds",
@@ -162,17 +237,17 @@ func TestDuration_MarshalJSON(t *testing.T) {
 		{
 			name: "days and hours",
 			d:    Duration(2*24*time.Hour + 5*time.Hour),
-			want: `"2d5h"`,
+			want: `"53h"`,
 		},
 		{
 			name: "days, hours and minutes",
 			d:    Duration(2*24*time.Hour + 5*time.Hour + 30*time.Minute),
-			want: `"2d5h30m"`,
+			want: `"53h30m"`,
 		},
 		{
 			name: "complex full duration",
 			d:    Duration(2*24*time.Hour + 5*time.Hour + 30*time.Minute + 15*time.Second),
-			want: `"2d5h30m15s"`,
+			want: `"53h30m15s"`,
 		},
 		{
 			name: "59 seconds",
@@ -202,6 +277,16 @@ func TestDuration_MarshalJSON(t *testing.T) {
 				t.Errorf("MarshalJSON() = %v, want %v", string(got), tt.want)
 			}
 
+			if !tt.wantErr && tt.d > 0 {
+				var s string
+				if err := json.Unmarshal(got, &s); err != nil {
+					t.Fatalf("MarshalJSON() = %s, not a string: %v", got, err)
+				}
+				if parsed, err := time.ParseDuration(s); err != nil || parsed != time.Duration(tt.d) {
+					t.Errorf("time.ParseDuration(%q) = %v, %v; want %v", s, parsed, err, time.Duration(tt.d))
+				}
+			}
+
 			if !tt.wantErr {
 				var decoded Duration
 				err = json.Unmarshal(got, &decoded)
@@ -215,3 +300,133 @@ func TestDuration_MarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestShowModelResponseAccessors(t *testing.T) {
+	body := `{
+		"details": {"parent_model": "", "format": "gguf", "family": "llama", "families": ["llama"], "parameter_size": "3.2B", "quantization_level": "Q4_K_M"},
+		"model_info": {"general.architecture": "llama", "general.parameter_count": 3212749888, "llama.context_length": 131072, "llama.embedding_length": 3072},
+		"capabilities": ["completion", "tools"],
+		"modified_at": "2024-09-25T10:00:00Z"
+	}`
+	var resp ShowModelResponse
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"Architecture", resp.Architecture(), "llama"},
+		{"ParameterCount", resp.ParameterCount(), int64(3212749888)},
+		{"ContextLength", resp.ContextLength(), 131072},
+		{"EmbeddingLength", resp.EmbeddingLength(), 3072},
+		{"SupportsTools", resp.SupportsTools(), true},
+		{"SupportsVision", resp.SupportsVision(), false},
+		{"SupportsEmbedding", resp.SupportsEmbedding(), false},
+		{"QuantizationLevel", resp.Details.QuantizationLevel, "Q4_K_M"},
+		{"ModifiedAt", resp.ModifiedAt, time.Date(2024, 9, 25, 10, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// Servers without capabilities report vision through a projector.
+	older := ShowModelResponse{ProjectorInfo: map[string]interface{}{"clip.has_vision_encoder": true}}
+	if !older.SupportsVision() || older.ContextLength() != 0 {
+		t.Errorf("SupportsVision() = %v, ContextLength() = %d, want true, 0", older.SupportsVision(), older.ContextLength())
+	}
+}
+
+func TestKeepAliveOmitted(t *testing.T) {
+	tests := []struct {
+		keepAlive Duration
+		want      string
+	}{
+		{0, `{"model":"llama2","prompt":"","stream":false}`},
+		{KeepForever, `{"model":"llama2","prompt":"","stream":false,"keep_alive":-1}`},
+		{UnloadNow, `{"model":"llama2","prompt":"","stream":false,"keep_alive":0}`},
+	}
+	for _, tt := range tests {
+		got, err := json.Marshal(GenerateRequest{Model: "llama2", KeepAlive: tt.keepAlive})
+		if err != nil {
+			t.Fatalf("json.Marshal() error = %v", err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("json.Marshal(keep_alive %d) = %s, want %s", tt.keepAlive, got, tt.want)
+		}
+	}
+}
+
+func TestDurationTimeDuration(t *testing.T) {
+	tests := []struct {
+		d    Duration
+		want time.Duration
+	}{
+		{0, 0},
+		{Duration(5 * time.Minute), 5 * time.Minute},
+		{KeepForever, -1},
+		{Duration(-time.Hour), -1},
+		{UnloadNow, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.d.TimeDuration(); got != tt.want {
+			t.Errorf("Duration(%d).TimeDuration() = %v, want %v", int64(tt.d), got, tt.want)
+		}
+	}
+}
+
+func FuzzDuration(f *testing.F) {
+	for _, s := range []string{"5m", "1.5h", "300ms", "-1s", "0s", "1h30m45.5s", "2562047h", ".5us", "1d2h"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		b, err := json.Marshal(s)
+		if err != nil {
+			t.Skip()
+		}
+		var d Duration
+		unmarshalErr := json.Unmarshal(b, &d)
+
+		if want, err := time.ParseDuration(s); err == nil {
+			if unmarshalErr != nil {
+				t.Fatalf("Unmarshal(%s) error = %v, but time.ParseDuration accepts it", b, unmarshalErr)
+			}
+			switch {
+			case want < 0 && d != KeepForever:
+				t.Fatalf("Unmarshal(%s) = %d, want KeepForever", b, d)
+			case want == 0 && d != UnloadNow:
+				t.Fatalf("Unmarshal(%s) = %d, want UnloadNow", b, d)
+			case want > 0 && d != Duration(want):
+				t.Fatalf("Unmarshal(%s) = %v, want %v", b, time.Duration(d), want)
+			}
+		}
+		if unmarshalErr != nil {
+			return
+		}
+
+		out, err := json.Marshal(d)
+		if err != nil {
+			t.Fatalf("Marshal(%d) error = %v", d, err)
+		}
+		if d > 0 {
+			var str string
+			if err := json.Unmarshal(out, &str); err != nil {
+				t.Fatalf("Marshal(%d) = %s, not a string: %v", d, out, err)
+			}
+			if parsed, err := time.ParseDuration(str); err != nil || parsed != time.Duration(d) {
+				t.Fatalf("time.ParseDuration(%q) = %v, %v; want %v", str, parsed, err, time.Duration(d))
+			}
+		}
+		var back Duration
+		if err := json.Unmarshal(out, &back); err != nil {
+			t.Fatalf("Unmarshal(%s) of marshalled %d error = %v", out, d, err)
+		}
+		if back != d {
+			t.Fatalf("round trip of %s via %s = %d, want %d", b, out, back, d)
+		}
+	})
+}
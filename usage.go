@@ -0,0 +1,188 @@
+package ollama
+
+import (
+	"sync"
+	"time"
+)
+
+// Usage summarises the token counts and timings the server reports for
+// completed requests.
+type Usage struct {
+	Requests           int           `json:"requests"`
+	PromptTokens       int           `json:"prompt_tokens"`
+	EvalTokens         int           `json:"eval_tokens"`
+	TotalDuration      time.Duration `json:"total_duration"`
+	LoadDuration       time.Duration `json:"load_duration"`
+	PromptEvalDuration time.Duration `json:"prompt_eval_duration"`
+	EvalDuration       time.Duration `json:"eval_duration"`
+}
+
+// TotalTokens returns the number of prompt and generated tokens.
+func (u Usage) TotalTokens() int {
+	return u.PromptTokens + u.EvalTokens
+}
+
+// PromptTokensPerSecond returns the prompt evaluation rate.
+func (u Usage) PromptTokensPerSecond() float64 {
+	return tokensPerSecond(u.PromptTokens, u.PromptEvalDuration)
+}
+
+// EvalTokensPerSecond returns the generation rate.
+func (u Usage) EvalTokensPerSecond() float64 {
+	return tokensPerSecond(u.EvalTokens, u.EvalDuration)
+}
+
+// Add accumulates other into u.
+func (u *Usage) Add(other Usage) {
+	u.Requests += other.Requests
+	u.PromptTokens += other.PromptTokens
+	u.EvalTokens += other.EvalTokens
+	u.TotalDuration += other.TotalDuration
+	u.LoadDuration += other.LoadDuration
+	u.PromptEvalDuration += other.PromptEvalDuration
+	u.EvalDuration += other.EvalDuration
+}
+
+func tokensPerSecond(tokens int, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(tokens) / d.Seconds()
+}
+
+// Usage returns the usage reported by the response. Only the final chunk
+// of a stream (Done set) carries statistics.
+func (r *GenerateResponse) Usage() Usage {
+	return newUsage(r.PromptEvalCount, r.EvalCount, r.TotalDuration, r.LoadDuration, r.PromptEvalDuration, r.EvalDuration)
+}
+
+// TotalTokens returns the number of prompt and generated tokens.
+func (r *GenerateResponse) TotalTokens() int {
+	return r.Usage().TotalTokens()
+}
+
+// PromptTokensPerSecond returns the prompt evaluation rate.
+func (r *GenerateResponse) PromptTokensPerSecond() float64 {
+	return r.Usage().PromptTokensPerSecond()
+}
+
+// EvalTokensPerSecond returns the generation rate.
+func (r *GenerateResponse) EvalTokensPerSecond() float64 {
+	return r.Usage().EvalTokensPerSecond()
+}
+
+// LoadTime returns the time spent loading the model.
+func (r *GenerateResponse) LoadTime() time.Duration {
+	return time.Duration(r.LoadDuration)
+}
+
+// Usage returns the usage reported by the response. Only the final chunk
+// of a stream (Done set) carries statistics.
+func (r *ChatResponse) Usage() Usage {
+	return newUsage(r.PromptEvalCount, r.EvalCount, r.TotalDuration, r.LoadDuration, r.PromptEvalDuration, r.EvalDuration)
+}
+
+// TotalTokens returns the number of prompt and generated tokens.
+func (r *ChatResponse) TotalTokens() int {
+	return r.Usage().TotalTokens()
+}
+
+// PromptTokensPerSecond returns the prompt evaluation rate.
+func (r *ChatResponse) PromptTokensPerSecond() float64 {
+	return r.Usage().PromptTokensPerSecond()
+}
+
+// EvalTokensPerSecond returns the generation rate.
+func (r *ChatResponse) EvalTokensPerSecond() float64 {
+	return r.Usage().EvalTokensPerSecond()
+}
+
+// LoadTime returns the time spent loading the model.
+func (r *ChatResponse) LoadTime() time.Duration {
+	return time.Duration(r.LoadDuration)
+}
+
+func newUsage(promptTokens, evalTokens int, total, load, promptEval, eval int64) Usage {
+	return Usage{
+		Requests:           1,
+		PromptTokens:       promptTokens,
+		EvalTokens:         evalTokens,
+		TotalDuration:      time.Duration(total),
+		LoadDuration:       time.Duration(load),
+		PromptEvalDuration: time.Duration(promptEval),
+		EvalDuration:       time.Duration(eval),
+	}
+}
+
+// UsageTracker accumulates usage per model. It is safe for concurrent use.
+type UsageTracker struct {
+	mu     sync.Mutex
+	models map[string]Usage
+}
+
+// NewUsageTracker creates an empty UsageTracker.
+func NewUsageTracker() *UsageTracker {
+	return &UsageTracker{models: make(map[string]Usage)}
+}
+
+// Record adds usage for model.
+func (t *UsageTracker) Record(model string, u Usage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	total := t.models[model]
+	total.Add(u)
+	t.models[model] = total
+}
+
+// Model returns the usage accumulated for model.
+func (t *UsageTracker) Model(model string) Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.models[model]
+}
+
+// Snapshot returns a copy of the usage accumulated per model.
+func (t *UsageTracker) Snapshot() map[string]Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	snapshot := make(map[string]Usage, len(t.models))
+	for model, u := range t.models {
+		snapshot[model] = u
+	}
+	return snapshot
+}
+
+// Total returns the usage accumulated across all models.
+func (t *UsageTracker) Total() Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var total Usage
+	for _, u := range t.models {
+		total.Add(u)
+	}
+	return total
+}
+
+// Reset clears all accumulated usage.
+func (t *UsageTracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.models = make(map[string]Usage)
+}
+
+// Usage returns the tracker accumulating usage for calls made by the client.
+func (c *Client) Usage() *UsageTracker {
+	return c.usage
+}
+
+// recordUsage adds the usage of a completed response to the client tracker,
+// unless the response came from a cache.
+func (c *Client) recordUsage(call *Call, model, fallback string, u Usage) {
+	if call.Cached {
+		return
+	}
+	if model == "" {
+		model = fallback
+	}
+	c.usage.Record(model, u)
+}
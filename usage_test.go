@@ -0,0 +1,86 @@
+package ollama
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestResponseUsage(t *testing.T) {
+	resp := &GenerateResponse{
+		TotalDuration:      int64(3 * time.Second),
+		LoadDuration:       int64(500 * time.Millisecond),
+		PromptEvalCount:    50,
+		PromptEvalDuration: int64(250 * time.Millisecond),
+		EvalCount:          100,
+		EvalDuration:       int64(2 * time.Second),
+	}
+
+	if got := resp.TotalTokens(); got != 150 {
+		t.Errorf("TotalTokens() = %d, want 150", got)
+	}
+	if got := resp.PromptTokensPerSecond(); got != 200 {
+		t.Errorf("PromptTokensPerSecond() = %v, want 200", got)
+	}
+	if got := resp.EvalTokensPerSecond(); got != 50 {
+		t.Errorf("EvalTokensPerSecond() = %v, want 50", got)
+	}
+	if got := resp.LoadTime(); got != 500*time.Millisecond {
+		t.Errorf("LoadTime() = %v, want 500ms", got)
+	}
+
+	var empty ChatResponse
+	if got := empty.EvalTokensPerSecond(); got != 0 {
+		t.Errorf("EvalTokensPerSecond() without stats = %v, want 0", got)
+	}
+}
+
+func TestClientUsageTracking(t *testing.T) {
+	tracker := NewUsageTracker()
+	_, client := newTestServer(t, withClientOptions(WithUsageTracker(tracker)))
+	ctx := context.Background()
+
+	if _, err := client.Generate(ctx, &GenerateRequest{Model: "llama3.2:1b", Prompt: "Hi"}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if _, err := client.Chat(ctx, &ChatRequest{Model: "qwen2.5:0.5b", Messages: []ChatMessage{{Role: UserRole, Content: "Hi"}}}); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+
+	stream, err := client.GenerateStream(ctx, &GenerateRequest{Model: "llama3.2:1b", Prompt: "Hi"})
+	if err != nil {
+		t.Fatalf("GenerateStream() error = %v", err)
+	}
+	for range stream {
+	}
+	chatStream, err := client.ChatStream(ctx, &ChatRequest{Model: "qwen2.5:0.5b", Messages: []ChatMessage{{Role: UserRole, Content: "Hi"}}})
+	if err != nil {
+		t.Fatalf("ChatStream() error = %v", err)
+	}
+	for range chatStream {
+	}
+
+	// The fake server reports 10 prompt and 20 eval tokens per request.
+	want := Usage{
+		Requests:           2,
+		PromptTokens:       20,
+		EvalTokens:         40,
+		TotalDuration:      time.Second,
+		LoadDuration:       200 * time.Millisecond,
+		PromptEvalDuration: 200 * time.Millisecond,
+		EvalDuration:       400 * time.Millisecond,
+	}
+	for _, model := range []string{"llama3.2:1b", "qwen2.5:0.5b"} {
+		if got := client.Usage().Model(model); got != want {
+			t.Errorf("Usage().Model(%q) = %+v, want %+v", model, got, want)
+		}
+	}
+	if got := tracker.Total().TotalTokens(); got != 120 {
+		t.Errorf("Total().TotalTokens() = %d, want 120", got)
+	}
+
+	tracker.Reset()
+	if got := len(tracker.Snapshot()); got != 0 {
+		t.Errorf("Snapshot() after Reset() has %d models", got)
+	}
+}